@@ -1,13 +1,21 @@
 package main
 
 import (
+	"errors"
 	"log"
+	"os"
 
 	"github.com/ipaqsa/artship/internal/command"
 )
 
 func main() {
 	if err := command.Run(); err != nil {
+		// The command has already reported the reason
+		var exitErr *command.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
+
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,347 @@
+package client
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ipaqsa/artship/internal/logs"
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+// maxReportedFiles limits the wasted and duplicated file lists in the text output
+const maxReportedFiles = 20
+
+// LayerAnalysis contains the size changes a single layer makes
+type LayerAnalysis struct {
+	Index        int    `json:"index"`
+	Digest       string `json:"digest"`
+	Size         int64  `json:"size"`         // Total size of the files in the layer
+	AddedBytes   int64  `json:"addedBytes"`   // Files not present in lower layers
+	ChangedBytes int64  `json:"changedBytes"` // Files replacing files from lower layers
+	RemovedBytes int64  `json:"removedBytes"` // Files from lower layers deleted by whiteouts
+	AddedFiles   int    `json:"addedFiles"`
+	ChangedFiles int    `json:"changedFiles"`
+	RemovedFiles int    `json:"removedFiles"`
+	WastedBytes  int64  `json:"wastedBytes"` // Bytes of this layer overwritten or deleted by later layers
+}
+
+// WastedFile is a path whose content is overwritten or deleted by later layers
+type WastedFile struct {
+	Path        string `json:"path"`
+	Layers      []int  `json:"layers"` // Layers containing the wasted copies
+	WastedBytes int64  `json:"wastedBytes"`
+}
+
+// DuplicateFile is a file content stored in more than one layer
+type DuplicateFile struct {
+	Hash        string   `json:"hash"`
+	Size        int64    `json:"size"`
+	Paths       []string `json:"paths"` // 'layer:path' locations of the copies
+	WastedBytes int64    `json:"wastedBytes"`
+}
+
+// AnalyzeResult contains the layer efficiency analysis of an image
+type AnalyzeResult struct {
+	Image          string          `json:"image"`
+	TotalSize      int64           `json:"totalSize"`   // Size of the files in all layers
+	ImageSize      int64           `json:"imageSize"`   // Size of the files in the final filesystem
+	WastedBytes    int64           `json:"wastedBytes"` // Bytes overwritten or deleted by later layers
+	DuplicateBytes int64           `json:"duplicateBytes"`
+	Efficiency     float64         `json:"efficiency"` // Percentage of the stored bytes present in the final filesystem
+	Layers         []LayerAnalysis `json:"layers"`
+	Wasted         []WastedFile    `json:"wasted"`
+	Duplicates     []DuplicateFile `json:"duplicates"`
+}
+
+// WastedPercent returns the wasted bytes as a percentage of the total size
+func (r *AnalyzeResult) WastedPercent() float64 {
+	if r.TotalSize == 0 {
+		return 0
+	}
+
+	return float64(r.WastedBytes) * 100 / float64(r.TotalSize)
+}
+
+// String returns formatted analysis output with colors
+func (r *AnalyzeResult) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(logs.BoldBlue("Layers:") + "\n")
+	sb.WriteString(fmt.Sprintf("  %-5s %-19s %10s %10s %10s %10s %10s\n",
+		"#", "DIGEST", "SIZE", "ADDED", "CHANGED", "REMOVED", "WASTED"))
+	for _, layer := range r.Layers {
+		wasted := tools.FormatSize(layer.WastedBytes)
+		if layer.WastedBytes > 0 {
+			wasted = logs.Red(fmt.Sprintf("%10s", wasted))
+		} else {
+			wasted = fmt.Sprintf("%10s", wasted)
+		}
+
+		sb.WriteString(fmt.Sprintf("  %-5d %-19s %10s %10s %10s %10s %s\n",
+			layer.Index, shortDigest(layer.Digest),
+			tools.FormatSize(layer.Size),
+			tools.FormatSize(layer.AddedBytes),
+			tools.FormatSize(layer.ChangedBytes),
+			tools.FormatSize(layer.RemovedBytes),
+			wasted))
+	}
+
+	if len(r.Wasted) > 0 {
+		sb.WriteString("\n" + logs.BoldBlue("Wasted space:") + "\n")
+		for i, file := range r.Wasted {
+			if i == maxReportedFiles {
+				sb.WriteString(logs.Gray(fmt.Sprintf("  ... and %d more\n", len(r.Wasted)-maxReportedFiles)))
+				break
+			}
+
+			sb.WriteString(fmt.Sprintf("  %10s  %s %s\n",
+				tools.FormatSize(file.WastedBytes), file.Path, logs.Gray(fmt.Sprintf("(layers %s)", joinInts(file.Layers)))))
+		}
+	}
+
+	if len(r.Duplicates) > 0 {
+		sb.WriteString("\n" + logs.BoldBlue("Duplicated files:") + "\n")
+		for i, duplicate := range r.Duplicates {
+			if i == maxReportedFiles {
+				sb.WriteString(logs.Gray(fmt.Sprintf("  ... and %d more\n", len(r.Duplicates)-maxReportedFiles)))
+				break
+			}
+
+			sb.WriteString(fmt.Sprintf("  %10s  %s\n", tools.FormatSize(duplicate.WastedBytes), strings.Join(duplicate.Paths, ", ")))
+		}
+	}
+
+	sb.WriteString("\n" + logs.BoldBlue("Summary:") + "\n")
+	sb.WriteString(fmt.Sprintf("  Total layer size:  %s\n", tools.FormatSize(r.TotalSize)))
+	sb.WriteString(fmt.Sprintf("  Image size:        %s\n", tools.FormatSize(r.ImageSize)))
+	sb.WriteString(fmt.Sprintf("  Wasted space:      %s (%.1f%%)\n", tools.FormatSize(r.WastedBytes), r.WastedPercent()))
+	sb.WriteString(fmt.Sprintf("  Duplicated files:  %s\n", tools.FormatSize(r.DuplicateBytes)))
+
+	efficiency := fmt.Sprintf("%.1f%%", r.Efficiency)
+	switch {
+	case r.Efficiency >= 95:
+		efficiency = logs.BoldGreen(efficiency)
+	case r.Efficiency >= 80:
+		efficiency = logs.BoldYellow(efficiency)
+	default:
+		efficiency = logs.BoldRed(efficiency)
+	}
+	sb.WriteString(fmt.Sprintf("  Efficiency:        %s\n", efficiency))
+
+	return sb.String()
+}
+
+// ToJSON returns JSON representation of the analysis result
+func (r *AnalyzeResult) ToJSON() (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal analyze result to JSON: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// analyzedFile is a file of the merged filesystem while layers are applied
+type analyzedFile struct {
+	layer int
+	size  int64
+	dir   bool
+}
+
+// Analyze walks each layer of the image and reports wasted and duplicated space
+func (c *Client) Analyze(ctx context.Context, imageRef string) (*AnalyzeResult, error) {
+	if imageRef == "" {
+		return nil, fmt.Errorf("no image ref provided")
+	}
+
+	layers, err := c.imageLayers(ctx, imageRef)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AnalyzeResult{
+		Image:      imageRef,
+		Layers:     make([]LayerAnalysis, len(layers)),
+		Wasted:     []WastedFile{},
+		Duplicates: []DuplicateFile{},
+	}
+
+	// Merged filesystem after applying the layers processed so far
+	merged := make(map[string]analyzedFile)
+	wasted := make(map[string]*WastedFile)
+	contents := make(map[string][]string)
+	sizes := make(map[string]int64)
+
+	waste := func(p string, file analyzedFile) {
+		if file.dir {
+			return
+		}
+
+		result.Layers[file.layer].WastedBytes += file.size
+		result.WastedBytes += file.size
+
+		entry, ok := wasted[p]
+		if !ok {
+			entry = &WastedFile{Path: p}
+			wasted[p] = entry
+		}
+		entry.Layers = append(entry.Layers, file.layer)
+		entry.WastedBytes += file.size
+	}
+
+	for i, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("get layer digest: %w", err)
+		}
+
+		c.logger.Debug("Analyzing layer %d: %s", i, digest)
+
+		analysis := &result.Layers[i]
+		analysis.Index = i
+		analysis.Digest = digest.String()
+
+		rc, err := layer.Uncompressed()
+		if err != nil {
+			return nil, fmt.Errorf("read the layer '%s': %w", digest, err)
+		}
+
+		changes, err := tools.ReadLayer(rc, func(r io.Reader, header *tar.Header) error {
+			if header.Typeflag != tar.TypeReg || header.Size == 0 {
+				return nil
+			}
+
+			hash := sha256.New()
+			if _, err := io.Copy(hash, r); err != nil {
+				return fmt.Errorf("read '%s': %w", header.Name, err)
+			}
+
+			sum := hex.EncodeToString(hash.Sum(nil))
+			contents[sum] = append(contents[sum], fmt.Sprintf("%d:%s", i, tools.CleanPath(header.Name)))
+			sizes[sum] = header.Size
+
+			return nil
+		})
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("analyze the layer '%s': %w", digest, err)
+		}
+
+		// Whiteouts apply to the lower layers only, so they are processed before the layer entries
+		tools.RemoveHidden(merged, changes, func(p string, file analyzedFile) {
+			if !file.dir {
+				analysis.RemovedBytes += file.size
+				analysis.RemovedFiles++
+			}
+
+			waste(p, file)
+		})
+
+		for p, header := range changes.Entries {
+			file := analyzedFile{
+				layer: i,
+				size:  header.Size,
+				dir:   header.Typeflag == tar.TypeDir,
+			}
+
+			if !file.dir {
+				analysis.Size += file.size
+			}
+
+			if lower, ok := merged[p]; ok {
+				waste(p, lower)
+				if !file.dir {
+					analysis.ChangedBytes += file.size
+					analysis.ChangedFiles++
+				}
+			} else if !file.dir {
+				analysis.AddedBytes += file.size
+				analysis.AddedFiles++
+			}
+
+			merged[p] = file
+		}
+
+		result.TotalSize += analysis.Size
+	}
+
+	for _, file := range merged {
+		result.ImageSize += file.size
+	}
+
+	if result.TotalSize > 0 {
+		result.Efficiency = float64(result.TotalSize-result.WastedBytes) * 100 / float64(result.TotalSize)
+	} else {
+		result.Efficiency = 100
+	}
+
+	for _, file := range wasted {
+		result.Wasted = append(result.Wasted, *file)
+	}
+	sort.Slice(result.Wasted, func(i, j int) bool {
+		if result.Wasted[i].WastedBytes != result.Wasted[j].WastedBytes {
+			return result.Wasted[i].WastedBytes > result.Wasted[j].WastedBytes
+		}
+		return result.Wasted[i].Path < result.Wasted[j].Path
+	})
+
+	for sum, paths := range contents {
+		if !spansLayers(paths) {
+			continue
+		}
+
+		duplicate := DuplicateFile{
+			Hash:        sum,
+			Size:        sizes[sum],
+			Paths:       paths,
+			WastedBytes: sizes[sum] * int64(len(paths)-1),
+		}
+		result.Duplicates = append(result.Duplicates, duplicate)
+		result.DuplicateBytes += duplicate.WastedBytes
+	}
+	sort.Slice(result.Duplicates, func(i, j int) bool {
+		if result.Duplicates[i].WastedBytes != result.Duplicates[j].WastedBytes {
+			return result.Duplicates[i].WastedBytes > result.Duplicates[j].WastedBytes
+		}
+		return result.Duplicates[i].Hash < result.Duplicates[j].Hash
+	})
+
+	return result, nil
+}
+
+// spansLayers checks if the 'layer:path' locations belong to more than one layer
+func spansLayers(paths []string) bool {
+	for _, p := range paths[1:] {
+		if strings.SplitN(p, ":", 2)[0] != strings.SplitN(paths[0], ":", 2)[0] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shortDigest shortens a digest for table output
+func shortDigest(digest string) string {
+	if len(digest) > 19 {
+		return digest[:19]
+	}
+
+	return digest
+}
+
+// joinInts joins integers with commas
+func joinInts(values []int) string {
+	res := make([]string, 0, len(values))
+	for _, v := range values {
+		res = append(res, fmt.Sprint(v))
+	}
+
+	return strings.Join(res, ",")
+}
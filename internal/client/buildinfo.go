@@ -0,0 +1,242 @@
+package client
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"debug/buildinfo"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/ipaqsa/artship/internal/logs"
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+// GoModule describes a Go module embedded in a binary
+type GoModule struct {
+	Path    string    `json:"path"`
+	Version string    `json:"version,omitempty"`
+	Sum     string    `json:"sum,omitempty"`
+	Replace *GoModule `json:"replace,omitempty"`
+}
+
+// GoBuildSetting is a single build setting (e.g. -ldflags, vcs.revision)
+type GoBuildSetting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// GoBinary contains the build information of a Go executable found in an image
+type GoBinary struct {
+	File      string           `json:"file"`
+	GoVersion string           `json:"goVersion"`
+	Package   string           `json:"package,omitempty"`
+	Main      GoModule         `json:"main"`
+	Deps      []GoModule       `json:"deps,omitempty"`
+	Settings  []GoBuildSetting `json:"settings,omitempty"`
+}
+
+// Setting returns the value of the build setting with the given key
+func (b GoBinary) Setting(key string) string {
+	for _, setting := range b.Settings {
+		if setting.Key == key {
+			return setting.Value
+		}
+	}
+
+	return ""
+}
+
+// GoBinaryList represents a collection of Go binaries
+type GoBinaryList []GoBinary
+
+// String returns formatted build information with colors
+func (l GoBinaryList) String() string {
+	if len(l) == 0 {
+		return "No Go binaries found"
+	}
+
+	var sb strings.Builder
+	for i, bin := range l {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+
+		sb.WriteString(logs.BoldBlue(bin.File))
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf("  Go version: %s\n", logs.Yellow(bin.GoVersion)))
+		if bin.Package != "" {
+			sb.WriteString(fmt.Sprintf("  Package:    %s\n", bin.Package))
+		}
+		sb.WriteString(fmt.Sprintf("  Module:     %s\n", formatGoModule(bin.Main)))
+
+		if revision := bin.Setting("vcs.revision"); revision != "" {
+			vcs := revision
+			if bin.Setting("vcs.modified") == "true" {
+				vcs += " (modified)"
+			}
+			sb.WriteString(fmt.Sprintf("  Revision:   %s\n", logs.Green(vcs)))
+		}
+
+		if len(bin.Settings) > 0 {
+			sb.WriteString("  Settings:\n")
+			for _, setting := range bin.Settings {
+				sb.WriteString(fmt.Sprintf("    %s=%s\n", setting.Key, logs.Gray(setting.Value)))
+			}
+		}
+
+		if len(bin.Deps) > 0 {
+			sb.WriteString(fmt.Sprintf("  Dependencies (%d):\n", len(bin.Deps)))
+			for _, dep := range bin.Deps {
+				sb.WriteString(fmt.Sprintf("    %s\n", formatGoModule(dep)))
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// ToJSON returns JSON representation of the build information
+func (l GoBinaryList) ToJSON() (string, error) {
+	if l == nil {
+		l = GoBinaryList{}
+	}
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal build info to JSON: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// formatGoModule formats a module as 'path version' with an optional replacement
+func formatGoModule(mod GoModule) string {
+	res := strings.TrimSpace(mod.Path + " " + mod.Version)
+	if mod.Replace != nil {
+		res += " => " + formatGoModule(*mod.Replace)
+	}
+
+	return res
+}
+
+// BuildInfo locates Go executables in the image and reads their build information.
+// If paths are provided, only matching files are inspected, otherwise all executable files are
+func (c *Client) BuildInfo(ctx context.Context, imageRef string, paths []string) (GoBinaryList, error) {
+	if imageRef == "" {
+		return nil, fmt.Errorf("no image ref provided")
+	}
+
+	matchers, err := tools.NewMatchers(paths, false)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := c.extractImage(ctx, imageRef)
+	if err != nil {
+		return nil, err
+	}
+	defer img.Close()
+
+	var binaries GoBinaryList
+	c.logger.Debug("Searching for Go binaries...")
+	err = tools.WalkTar(img, func(r io.Reader, header *tar.Header) error {
+		if header.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		if len(paths) > 0 {
+			if !tools.MatchAny(matchers, header.Name) {
+				return nil
+			}
+		} else if header.Mode&0o111 == 0 {
+			return nil
+		}
+
+		bin, err := readGoBinary(r, header)
+		if err != nil {
+			c.logger.Debug("Skipping %s: %v", header.Name, err)
+			return nil
+		}
+
+		c.logger.Debug("Found Go binary: %s (%s)", header.Name, bin.GoVersion)
+		binaries = append(binaries, *bin)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk the image: %w", err)
+	}
+
+	c.logger.Debug("Found %d Go binaries", len(binaries))
+	return binaries, nil
+}
+
+// readGoBinary reads build information of a single executable from the tar stream
+func readGoBinary(r io.Reader, header *tar.Header) (*GoBinary, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("read file header: %w", err)
+	}
+
+	if !tools.IsExecutable(magic) {
+		return nil, fmt.Errorf("not an executable")
+	}
+
+	// Build info needs random access, the executable goes to a temporary file instead of memory
+	tmp, err := tools.SpoolChecked(br, "")
+	if err != nil {
+		return nil, fmt.Errorf("read file content: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	info, err := buildinfo.Read(tmp)
+	if err != nil {
+		return nil, fmt.Errorf("read build info: %w", err)
+	}
+
+	return newGoBinary(header.Name, info), nil
+}
+
+// newGoBinary converts runtime build info to GoBinary
+func newGoBinary(file string, info *debug.BuildInfo) *GoBinary {
+	bin := &GoBinary{
+		File:      file,
+		GoVersion: info.GoVersion,
+		Package:   info.Path,
+		Main:      newGoModule(&info.Main),
+	}
+
+	for _, dep := range info.Deps {
+		bin.Deps = append(bin.Deps, newGoModule(dep))
+	}
+
+	for _, setting := range info.Settings {
+		bin.Settings = append(bin.Settings, GoBuildSetting{Key: setting.Key, Value: setting.Value})
+	}
+
+	return bin
+}
+
+// newGoModule converts runtime module info to GoModule
+func newGoModule(mod *debug.Module) GoModule {
+	res := GoModule{
+		Path:    mod.Path,
+		Version: mod.Version,
+		Sum:     mod.Sum,
+	}
+
+	if mod.Replace != nil {
+		replace := newGoModule(mod.Replace)
+		res.Replace = &replace
+	}
+
+	return res
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/ipaqsa/artship/internal/tools"
 )
 
-func (c *Client) Cat(ctx context.Context, imageRef, artifact string) ([]byte, error) {
+// Cat returns the content of the first regular file matching the artifact name, glob or,
+// with regex, regular expression. A layer digest or index reads the file from that layer only
+func (c *Client) Cat(ctx context.Context, imageRef, layer, artifact string, regex bool) ([]byte, error) {
 	if imageRef == "" {
 		return nil, fmt.Errorf("no image ref provided")
 	}
@@ -18,7 +20,12 @@ func (c *Client) Cat(ctx context.Context, imageRef, artifact string) ([]byte, er
 		return nil, fmt.Errorf("no artifact provided")
 	}
 
-	img, err := c.extractImage(ctx, imageRef)
+	matcher, err := tools.NewMatcher(artifact, regex)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := c.extract(ctx, imageRef, layer)
 	if err != nil {
 		return nil, err
 	}
@@ -27,7 +34,7 @@ func (c *Client) Cat(ctx context.Context, imageRef, artifact string) ([]byte, er
 	var content []byte
 	c.logger.Debug("Searching for artifact...")
 	err = tools.WalkTar(img, func(r io.Reader, header *tar.Header) error {
-		if tools.MatchName(header.Name, artifact) && header.Typeflag == tar.TypeReg {
+		if matcher.Match(header.Name) && header.Typeflag == tar.TypeReg {
 			c.logger.Debug("Found artifact: %s (size: %d bytes)", header.Name, header.Size)
 			content, err = io.ReadAll(r)
 			if err != nil {
@@ -2,7 +2,6 @@ package client
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -10,10 +9,10 @@ import (
 	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/name"
 	crv1 "github.com/google/go-containerregistry/pkg/v1"
-	"github.com/google/go-containerregistry/pkg/v1/mutate"
 	"github.com/google/go-containerregistry/pkg/v1/remote"
 
 	"github.com/ipaqsa/artship/internal/logs"
+	"github.com/ipaqsa/artship/internal/tools"
 )
 
 const (
@@ -68,6 +67,7 @@ func setupRemoteOptions(username, password, auth, token string) []remote.Option
 	return remoteOpts
 }
 
+// extract returns the merged filesystem of the image, or a single layer by digest or index when it is set
 func (c *Client) extract(ctx context.Context, imageRef, layer string) (io.ReadCloser, error) {
 	if len(layer) == 0 {
 		return c.extractImage(ctx, imageRef)
@@ -76,46 +76,51 @@ func (c *Client) extract(ctx context.Context, imageRef, layer string) (io.ReadCl
 	return c.extractLayer(ctx, imageRef, layer)
 }
 
+// extractImage returns the merged filesystem of the image as a tar stream with whiteouts applied
 func (c *Client) extractImage(ctx context.Context, imageRef string) (io.ReadCloser, error) {
-	img, err := c.image(ctx, imageRef)
+	layers, err := c.imageLayers(ctx, imageRef)
 	if err != nil {
 		return nil, err
 	}
 
-	return mutate.Extract(img), nil
+	openers := make([]tools.LayerOpener, len(layers))
+	for i, layer := range layers {
+		openers[i] = layer.Uncompressed
+	}
+
+	return tools.FlattenLayers(openers), nil
 }
 
-func (c *Client) extractLayer(ctx context.Context, imageRef, layerDigest string) (io.ReadCloser, error) {
-	img, err := c.image(ctx, imageRef)
+// extractLayer returns the raw tar stream of a single layer, selected by digest or index.
+// Whiteout markers stay in the stream, WalkTar skips them
+func (c *Client) extractLayer(ctx context.Context, imageRef, layer string) (io.ReadCloser, error) {
+	layers, err := c.imageLayers(ctx, imageRef)
 	if err != nil {
 		return nil, err
 	}
 
-	digest, err := crv1.NewHash(layerDigest)
+	index, err := selectLayer(layers, layer)
 	if err != nil {
-		return nil, fmt.Errorf("parse layer digest: %w", err)
+		return nil, err
 	}
 
-	// Get layers
-	layers, err := img.Layers()
+	c.logger.Debug("Reading layer %d of %d: %s", index, len(layers), layer)
+	return layers[index].Uncompressed()
+}
+
+// imageLayers returns the image layers ordered from the base layer up
+func (c *Client) imageLayers(ctx context.Context, imageRef string) ([]crv1.Layer, error) {
+	img, err := c.image(ctx, imageRef)
 	if err != nil {
-		return nil, fmt.Errorf("get image layers: %w", err)
+		return nil, err
 	}
 
-	// Find the layer with matching digest
-	for _, layer := range layers {
-		layerHash, err := layer.Digest()
-		if err != nil {
-			continue
-		}
-
-		if layerHash == digest {
-			c.logger.Debug("Found matching layer: %s", layerDigest)
-			return layer.Uncompressed()
-		}
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("get image layers: %w", err)
 	}
 
-	return nil, errors.New("layer not found")
+	return layers, nil
 }
 
 func (c *Client) image(_ context.Context, imageRef string) (crv1.Image, error) {
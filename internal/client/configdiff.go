@@ -0,0 +1,225 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ipaqsa/artship/internal/logs"
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+// ConfigChange is a single difference between image configurations
+type ConfigChange struct {
+	Field  string     `json:"field"`
+	Key    string     `json:"key,omitempty"` // Env variable, label, annotation or port
+	Status FileStatus `json:"status"`
+	Old    string     `json:"old,omitempty"`
+	New    string     `json:"new,omitempty"`
+}
+
+// ConfigDiffResult contains the configuration comparison results
+type ConfigDiffResult struct {
+	SourceImage string         `json:"source_image"`
+	TargetImage string         `json:"target_image"`
+	Changes     []ConfigChange `json:"changes"`
+	SizeDelta   int64          `json:"size_delta"` // Compressed size difference in bytes
+}
+
+// String returns formatted config diff output with colors
+func (r *ConfigDiffResult) String() string {
+	var sb strings.Builder
+
+	sb.WriteString("\n")
+	sb.WriteString(logs.BoldBlue(fmt.Sprintf("Comparing config %s → %s", r.SourceImage, r.TargetImage)))
+	sb.WriteString("\n")
+	sb.WriteString(logs.Gray("─────────────────────────────────────────────────────────────"))
+	sb.WriteString("\n\n")
+
+	if len(r.Changes) == 0 {
+		sb.WriteString(logs.BoldGreen("✓ No configuration differences") + "\n")
+		return sb.String()
+	}
+
+	for _, change := range r.Changes {
+		name := change.Field
+		if change.Key != "" {
+			name += " " + change.Key
+		}
+
+		var details string
+		switch change.Status {
+		case FileStatusAdded:
+			details = keyedValue(change.New)
+		case FileStatusRemoved:
+			details = keyedValue(change.Old)
+		default:
+			details = fmt.Sprintf("%s → %s", orNone(change.Old), orNone(change.New))
+		}
+
+		sb.WriteString(logs.FormatDiffLine(string(change.Status), name, details))
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+// ToJSON returns JSON representation of the config diff result
+func (r *ConfigDiffResult) ToJSON() (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal config diff result to JSON: %w", err)
+	}
+	return string(data), nil
+}
+
+// DiffConfig compares the metadata and configuration of two images field by field
+func (c *Client) DiffConfig(ctx context.Context, image1Ref, image2Ref string) (*ConfigDiffResult, error) {
+	if image1Ref == "" || image2Ref == "" {
+		return nil, fmt.Errorf("both image references must be provided")
+	}
+
+	if strings.HasPrefix(image1Ref, dirPrefix) || strings.HasPrefix(image2Ref, dirPrefix) {
+		return nil, fmt.Errorf("configuration can be compared only between images")
+	}
+
+	c.logger.Info("Analyzing %s...", image1Ref)
+	source, err := c.GetImageMeta(ctx, image1Ref)
+	if err != nil {
+		return nil, fmt.Errorf("get metadata of %s: %w", image1Ref, err)
+	}
+
+	c.logger.Info("Analyzing %s...", image2Ref)
+	target, err := c.GetImageMeta(ctx, image2Ref)
+	if err != nil {
+		return nil, fmt.Errorf("get metadata of %s: %w", image2Ref, err)
+	}
+
+	result := &ConfigDiffResult{
+		SourceImage: image1Ref,
+		TargetImage: image2Ref,
+		Changes:     []ConfigChange{},
+		SizeDelta:   target.SizeBytes - source.SizeBytes,
+	}
+
+	add := func(field, oldValue, newValue string) {
+		if oldValue != newValue {
+			result.Changes = append(result.Changes, ConfigChange{
+				Field:  field,
+				Status: FileStatusModified,
+				Old:    oldValue,
+				New:    newValue,
+			})
+		}
+	}
+
+	add("os", source.OS, target.OS)
+	add("architecture", source.Architecture, target.Architecture)
+	add("user", source.User, target.User)
+	add("workingDir", source.WorkingDir, target.WorkingDir)
+	add("entrypoint", formatCommand(source.Entrypoint), formatCommand(target.Entrypoint))
+	add("cmd", formatCommand(source.Cmd), formatCommand(target.Cmd))
+
+	result.Changes = append(result.Changes, diffMaps("env", envMap(source.Env), envMap(target.Env))...)
+	result.Changes = append(result.Changes, diffMaps("labels", source.Labels, target.Labels)...)
+	result.Changes = append(result.Changes, diffMaps("annotations", source.Annotations, target.Annotations)...)
+	result.Changes = append(result.Changes, diffMaps("exposedPorts", setMap(source.ExposedPorts), setMap(target.ExposedPorts))...)
+
+	add("layers", fmt.Sprint(len(source.Layers)), fmt.Sprint(len(target.Layers)))
+	if result.SizeDelta != 0 {
+		sign := "+"
+		delta := result.SizeDelta
+		if delta < 0 {
+			sign, delta = "-", -delta
+		}
+
+		add("size", source.Size, fmt.Sprintf("%s (%s%s)", target.Size, sign, tools.FormatSize(delta)))
+	}
+
+	return result, nil
+}
+
+// diffMaps compares two maps key by key, changes are sorted by key
+func diffMaps(field string, source, target map[string]string) []ConfigChange {
+	keys := slices.Collect(maps.Keys(source))
+	for key := range target {
+		if _, ok := source[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	var changes []ConfigChange
+	for _, key := range keys {
+		oldValue, inSource := source[key]
+		newValue, inTarget := target[key]
+
+		change := ConfigChange{Field: field, Key: key, Old: oldValue, New: newValue}
+		switch {
+		case !inSource:
+			change.Status = FileStatusAdded
+		case !inTarget:
+			change.Status = FileStatusRemoved
+		case oldValue != newValue:
+			change.Status = FileStatusModified
+		default:
+			continue
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// envMap converts 'KEY=value' environment entries to a map
+func envMap(env []string) map[string]string {
+	res := make(map[string]string, len(env))
+	for _, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		res[key] = value
+	}
+
+	return res
+}
+
+// setMap converts a list of values to a map with empty values
+func setMap(values []string) map[string]string {
+	res := make(map[string]string, len(values))
+	for _, value := range values {
+		res[value] = ""
+	}
+
+	return res
+}
+
+// formatCommand formats an exec form command as JSON, empty if it is not set
+func formatCommand(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+
+	data, _ := json.Marshal(args)
+	return string(data)
+}
+
+// keyedValue formats the value of an added or removed key
+func keyedValue(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return "= " + value
+}
+
+// orNone replaces empty values for display
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+
+	return value
+}
@@ -3,14 +3,68 @@ package client
 import (
 	"archive/tar"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/ipaqsa/artship/internal/logs"
 	"github.com/ipaqsa/artship/internal/tools"
 )
 
-func (c *Client) Copy(ctx context.Context, imageRef string, artifacts []string, output string) error {
+// CopyOptions contains options for copying artifacts
+type CopyOptions struct {
+	WithDeps        bool // Copy shared library dependencies of ELF binaries, preserving the image layout
+	Regex           bool // Treat artifacts as regular expressions instead of names and globs
+	PreservePaths   bool // Keep image paths inside the output directory instead of base names
+	StripComponents int  // Remove leading components of image paths, implies PreservePaths
+
+	SHA256        []string // Expected checksums of image files as 'path=hex', each must be verified
+	ChecksumsFile string   // SHA256SUMS file with expected checksums of image files
+	EmitChecksums bool     // Write a SHA256SUMS file for the copied files
+
+	Limits *tools.Limits // Abort the copy when it writes too much
+
+	Layer  string // Copy from a single layer, by digest or index, instead of the merged image
+	Strict bool   // Fail on links leaving the output, including symlinks with absolute targets
+}
+
+// checksumsFileName is the name of the emitted checksums file
+const checksumsFileName = "SHA256SUMS"
+
+// copiedFile is an image file written by a copy
+type copiedFile struct {
+	Path   string
+	Target string
+}
+
+// pendingLink is a copied hardlink whose target file is not copied,
+// it gets the content of the target from the image after the walk
+type pendingLink struct {
+	header   *tar.Header
+	target   string
+	expected string
+}
+
+// copySpec is a requested artifact and where its matches are written
+type copySpec struct {
+	source  string
+	matcher *tools.Matcher
+	output  string
+	mapped  bool // The artifact has its own destination ('src=dst')
+	matched int
+	copied  []copiedFile
+}
+
+// Copy copies artifacts from the image to the output path.
+// An artifact written as 'src=dst' is copied to its own destination instead of the output.
+// The output '-' writes the matched entries to stdout as a tar stream with image paths.
+// Every artifact pattern must match at least one path
+func (c *Client) Copy(ctx context.Context, imageRef string, artifacts []string, output string, opts *CopyOptions) error {
 	if imageRef == "" {
 		return fmt.Errorf("no image ref provided")
 	}
@@ -23,28 +77,413 @@ func (c *Client) Copy(ctx context.Context, imageRef string, artifacts []string,
 		return fmt.Errorf("no output provided")
 	}
 
-	img, err := c.extractImage(ctx, imageRef)
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+
+	specs, err := parseCopySpecs(artifacts, output, opts.Regex)
+	if err != nil {
+		return err
+	}
+
+	checksums, required, err := loadChecksums(opts)
+	if err != nil {
+		return err
+	}
+
+	if output == "-" && opts.EmitChecksums {
+		return fmt.Errorf("checksums can not be emitted when streaming to stdout")
+	}
+
+	if output == "-" && opts.WithDeps {
+		return fmt.Errorf("dependencies can not be copied when streaming to stdout")
+	}
+
+	if opts.WithDeps {
+		if opts.Layer != "" {
+			return fmt.Errorf("dependencies can not be resolved in a single layer")
+		}
+
+		matchers := make([]*tools.Matcher, 0, len(specs))
+		for _, spec := range specs {
+			if spec.mapped {
+				return fmt.Errorf("artifact mappings can not be used with dependencies")
+			}
+
+			matchers = append(matchers, spec.matcher)
+		}
+
+		return c.copyWithDeps(ctx, imageRef, matchers, output, opts.Limits, opts.Strict)
+	}
+
+	img, err := c.extract(ctx, imageRef, opts.Layer)
 	if err != nil {
 		return err
 	}
 	defer img.Close()
 
-	var found int
+	var stream *tar.Writer
+	if output == "-" {
+		stream = tar.NewWriter(os.Stdout)
+	}
+
+	var copied int
+	targets := make(map[string]*tar.Header)
+	sums := make(map[string]string)
+	verified := make(map[string]bool)
+	// Hardlinks are resolved against the copies of their targets: the first copy
+	// of every image file, or the file content read again if it is not selected
+	copies := make(map[string]string)
+	pending := make(map[string][]pendingLink)
+
+	// A failed copy removes the files it created, streamed entries can not be taken back
+	limiter := tools.NewLimiter(opts.Limits)
+	var created tools.CreatedPaths
+	fail := func(err error) error {
+		if cleanupErr := created.Remove(); cleanupErr != nil {
+			c.logger.Warn("Could not remove the partial output: %v", cleanupErr)
+		}
+
+		return err
+	}
+
 	c.logger.Debug("Searching for artifacts...")
 	err = tools.WalkTar(img, func(r io.Reader, header *tar.Header) error {
-		for _, artifact := range artifacts {
-			if tools.MatchName(header.Name, artifact) {
-				c.logger.Debug("Found artifact: %s (matches %s)", header.Name, artifact)
-				found++
-				if err = tools.CopyArtifact(r, header, output); err != nil {
-					return fmt.Errorf("copy the artifact '%s': %w", artifact, err)
+		p := tools.CleanPath(header.Name)
+
+		checked := header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeLink
+
+		// The first written copy is the source for other destinations of the same file
+		var written, writtenSum string
+		for _, spec := range specs {
+			if !spec.matcher.Match(header.Name) {
+				continue
+			}
+			spec.matched++
+
+			target, ok, err := spec.target(header.Name, opts)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				c.logger.Debug("Skipping %s: no path components left after stripping", header.Name)
+				continue
+			}
+
+			prev, exists := targets[target]
+			if exists && prev.Name == header.Name {
+				// Another artifact selected the same file for the same destination
+				spec.copied = append(spec.copied, copiedFile{Path: header.Name, Target: target})
+				continue
+			}
+
+			// Directories are merged, anything else would be overwritten
+			if exists && (prev.Typeflag != tar.TypeDir || header.Typeflag != tar.TypeDir) {
+				return fmt.Errorf("'%s' and '%s' are both copied to '%s'", prev.Name, header.Name, target)
+			}
+			targets[target] = header
+
+			expected := expectedChecksum(checksums, p, target, output, stream != nil, checked)
+
+			if opts.Strict {
+				if err = tools.CheckLink(header); err != nil {
+					return err
 				}
-				c.logger.Info(logs.Green("✓")+" Copied: %s", logs.Blue(header.Name))
 			}
+
+			if err = limiter.Check(header); err != nil {
+				return err
+			}
+			if stream == nil {
+				created.Track(target)
+			}
+
+			// Checksums are verified while the content is written, before it reaches the target
+			var sum string
+			var deferred bool
+			switch {
+			case header.Typeflag == tar.TypeLink:
+				link := tools.CleanPath(header.Linkname)
+				source, ok := copies[link]
+				if !ok {
+					pending[link] = append(pending[link], pendingLink{header: header, target: target, expected: expected})
+					deferred = true
+					break
+				}
+
+				sum = sums[source]
+				if err = tools.VerifyChecksum(sum, expected); err != nil {
+					break
+				}
+
+				if stream != nil {
+					entry := *header
+					entry.Linkname = source
+					_, err = writeTarEntry(stream, r, &entry, target, "")
+				} else {
+					err = tools.LinkOrCopy(source, target)
+				}
+			case stream != nil:
+				sum, err = writeTarEntry(stream, r, header, target, expected)
+			case written == "" && header.Typeflag == tar.TypeReg:
+				sum, err = tools.CopyFileChecked(r, header, target, expected)
+			default:
+				// The content was verified against the checksum of the first copy, compare this one too
+				if err = tools.VerifyChecksum(writtenSum, expected); err != nil {
+					break
+				}
+
+				err = copyEntryTo(r, header, written, target)
+				sum = writtenSum
+			}
+			if err != nil {
+				return fmt.Errorf("copy the artifact '%s': %w", header.Name, err)
+			}
+
+			if written == "" {
+				written, writtenSum = target, sum
+			}
+
+			if _, ok := copies[p]; !ok && header.Typeflag == tar.TypeReg {
+				copies[p] = target
+			}
+
+			if sum != "" {
+				sums[target] = sum
+			}
+
+			if expected != "" && !deferred {
+				verified[p] = true
+				c.logger.Debug("Verified SHA256 of %s", header.Name)
+			}
+
+			copied++
+			spec.copied = append(spec.copied, copiedFile{Path: header.Name, Target: target})
+			c.logger.Debug("Copied: %s -> %s", header.Name, target)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fail(fmt.Errorf("walk image: %w", err))
+	}
+
+	if len(pending) > 0 {
+		if err = c.copyLinkTargets(ctx, imageRef, opts.Layer, pending, stream, sums, verified); err != nil {
+			return fail(err)
 		}
+	}
+
+	if stream != nil {
+		if err = stream.Close(); err != nil {
+			return fmt.Errorf("write tar stream: %w", err)
+		}
+	}
+
+	for p := range required {
+		if !verified[p] {
+			return fmt.Errorf("checksum of '%s' is not verified: the file is not copied", p)
+		}
+	}
+
+	if len(verified) > 0 {
+		c.logger.Info(logs.Green("✓")+" Verified SHA256 of %d files", len(verified))
+	}
+
+	if opts.EmitChecksums {
+		name, err := writeChecksumsFile(output, sums)
+		if err != nil {
+			return err
+		}
+
+		c.logger.Info(logs.Green("✓")+" Wrote checksums of %d files to %s", len(sums), logs.Blue(name))
+	}
+
+	// Report the paths selected by each artifact
+	var unmatched []string
+	for _, spec := range specs {
+		name := spec.source
+		if spec.mapped {
+			name += " → " + spec.output
+		}
+
+		if spec.matched == 0 {
+			c.logger.Info(logs.Red("✗")+" %s matched nothing", logs.Yellow(name))
+			unmatched = append(unmatched, fmt.Sprintf("'%s'", spec.source))
+			continue
+		}
+
+		c.logger.Info(logs.Green("✓")+" %s matched %d paths:", logs.Yellow(name), spec.matched)
+		for _, file := range spec.copied {
+			c.logger.Info("    %s %s", logs.Blue(file.Path), logs.Gray("→ "+file.Target))
+		}
+	}
+
+	if len(unmatched) > 0 {
+		return fmt.Errorf("no artifacts match %s", strings.Join(unmatched, ", "))
+	}
+
+	c.logger.Info(logs.BoldGreen(fmt.Sprintf("✓ Successfully copied %d artifacts", copied)))
+
+	return nil
+}
+
+// parseCopySpecs parses the artifacts and their 'src=dst' mappings, rejecting invalid,
+// duplicate and conflicting ones before anything is downloaded
+func parseCopySpecs(artifacts []string, output string, regex bool) ([]*copySpec, error) {
+	specs := make([]*copySpec, 0, len(artifacts))
+	sources := make(map[string]bool)
+	destinations := make(map[string]string)
+
+	for _, artifact := range artifacts {
+		spec := &copySpec{source: artifact, output: output}
+
+		if source, destination, ok := strings.Cut(artifact, "="); ok {
+			if source == "" || destination == "" {
+				return nil, fmt.Errorf("invalid artifact mapping '%s', expected 'src=dst'", artifact)
+			}
+
+			if output == "-" {
+				return nil, fmt.Errorf("artifact mappings can not be used when streaming to stdout")
+			}
 
-		if found == len(artifacts) {
-			c.logger.Debug("All artifacts found, stopping search")
+			spec.source, spec.output, spec.mapped = source, destination, true
+
+			// Several artifacts can be copied into one directory, but not to one file
+			if !tools.IsDirTarget(destination) {
+				key := filepath.Clean(destination)
+				if other, exists := destinations[key]; exists {
+					return nil, fmt.Errorf("artifacts '%s' and '%s' are both mapped to the file '%s'", other, source, destination)
+				}
+				destinations[key] = source
+			}
+		}
+
+		if sources[spec.source] {
+			return nil, fmt.Errorf("artifact '%s' is requested more than once", spec.source)
+		}
+		sources[spec.source] = true
+
+		var err error
+		if spec.matcher, err = tools.NewMatcher(spec.source, regex); err != nil {
+			return nil, err
+		}
+
+		specs = append(specs, spec)
+	}
+
+	return specs, nil
+}
+
+// target returns the output path of an image file, false if the file is skipped.
+// A mapped path or directory is renamed to the destination, keeping the layout below it;
+// a source ending with a slash copies the directory contents into the destination.
+// Image paths below the destination are resolved with symlinks copied before kept inside it
+func (s *copySpec) target(name string, opts *CopyOptions) (string, bool, error) {
+	if !s.mapped || s.matcher.IsPattern() {
+		return artifactTarget(name, s.output, opts)
+	}
+
+	source := tools.CleanPath(s.source)
+	p := tools.CleanPath(name)
+
+	// Files matched by their name are the root of the copy themselves
+	root := p
+	if p == source || strings.HasPrefix(p, source+"/") {
+		root = source
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(p, root), "/")
+
+	base := s.output
+	if !strings.HasSuffix(s.source, "/") && tools.IsDirTarget(s.output) {
+		base = filepath.Join(s.output, path.Base(root))
+	}
+
+	target, err := tools.SecureJoin(base, rel)
+	return target, err == nil, err
+}
+
+// copyEntryTo writes the entry to the target. If the entry has already been written,
+// the content is copied from there since the tar reader can be read only once
+func copyEntryTo(r io.Reader, header *tar.Header, written, target string) error {
+	if written == "" || header.Typeflag != tar.TypeReg {
+		return tools.CopyEntry(r, header, target)
+	}
+
+	f, err := os.Open(written)
+	if err != nil {
+		return fmt.Errorf("open the copy '%s': %w", written, err)
+	}
+	defer f.Close()
+
+	return tools.CopyEntry(f, header, target)
+}
+
+// copyLinkTargets reads the image again for the content of hardlink targets that are not copied.
+// The first link of a target gets the content, the others are linked to it
+func (c *Client) copyLinkTargets(ctx context.Context, imageRef, layer string, pending map[string][]pendingLink,
+	stream *tar.Writer, sums map[string]string, verified map[string]bool) error {
+	c.logger.Debug("Copying the targets of %d hardlinks...", len(pending))
+
+	img, err := c.extract(ctx, imageRef, layer)
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+
+	err = tools.WalkTar(img, func(r io.Reader, header *tar.Header) error {
+		p := tools.CleanPath(header.Name)
+		links, ok := pending[p]
+		if !ok || header.Typeflag != tar.TypeReg {
+			return nil
+		}
+		delete(pending, p)
+
+		// Links share the content, any of their checksums applies to it
+		var expected string
+		for _, link := range links {
+			if link.expected != "" {
+				expected = link.expected
+				break
+			}
+		}
+
+		first := links[0]
+		var sum string
+		if stream != nil {
+			sum, err = writeTarEntry(stream, r, header, first.target, expected)
+		} else {
+			sum, err = tools.CopyFileChecked(r, header, first.target, expected)
+		}
+		if err != nil {
+			return fmt.Errorf("copy the hardlink '%s': %w", first.header.Name, err)
+		}
+
+		for _, link := range links {
+			if err = tools.VerifyChecksum(sum, link.expected); err != nil {
+				return fmt.Errorf("copy the hardlink '%s': %w", link.header.Name, err)
+			}
+
+			if link.target != first.target {
+				if stream != nil {
+					entry := *link.header
+					entry.Linkname = first.target
+					_, err = writeTarEntry(stream, nil, &entry, link.target, "")
+				} else {
+					err = tools.LinkOrCopy(first.target, link.target)
+				}
+				if err != nil {
+					return fmt.Errorf("copy the hardlink '%s': %w", link.header.Name, err)
+				}
+			}
+
+			sums[link.target] = sum
+			if link.expected != "" {
+				verified[tools.CleanPath(link.header.Name)] = true
+			}
+		}
+
+		if len(pending) == 0 {
 			return tools.ErrStopWalk
 		}
 
@@ -54,11 +493,165 @@ func (c *Client) Copy(ctx context.Context, imageRef string, artifacts []string,
 		return fmt.Errorf("walk image: %w", err)
 	}
 
-	if found < len(artifacts) {
-		c.logger.Info(logs.Yellow("⚠")+" Warning: Only found %d of %d requested artifacts", found, len(artifacts))
-	} else {
-		c.logger.Info(logs.BoldGreen(fmt.Sprintf("✓ Successfully copied %d artifacts", found)))
+	for target, links := range pending {
+		return fmt.Errorf("hardlink target '%s' of '%s' is not found in the image", target, links[0].header.Name)
 	}
 
 	return nil
 }
+
+// writeTarEntry writes the entry to the tar stream under the name, keeping its mode, owner and link,
+// and returns the checksum of a regular file. Content with an expected checksum is verified
+// before it is written, since the stream can not be taken back
+func writeTarEntry(tw *tar.Writer, r io.Reader, header *tar.Header, name string, expected string) (string, error) {
+	entry := *header
+	entry.Name = name
+	if entry.Typeflag == tar.TypeDir {
+		entry.Name += "/"
+	}
+	// PAX removes the name length limit of USTAR
+	entry.Format = tar.FormatPAX
+
+	if expected != "" {
+		spooled, err := tools.SpoolChecked(r, expected)
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(spooled.Name())
+		defer spooled.Close()
+
+		r = spooled
+	}
+
+	if err := tw.WriteHeader(&entry); err != nil {
+		return "", fmt.Errorf("write tar header: %w", err)
+	}
+
+	if entry.Typeflag != tar.TypeReg {
+		return "", nil
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tw, h), r); err != nil {
+		return "", fmt.Errorf("write tar content: %w", err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// artifactTarget returns the output path of an image file, false if the file is skipped.
+// Streamed entries keep their image paths
+func artifactTarget(name, output string, opts *CopyOptions) (string, bool, error) {
+	if output == "-" {
+		p, ok := tools.StripComponents(name, opts.StripComponents)
+		return p, ok, nil
+	}
+
+	if !opts.PreservePaths && opts.StripComponents == 0 {
+		return tools.ArtifactPath(name, output), true, nil
+	}
+
+	p, ok := tools.StripComponents(name, opts.StripComponents)
+	if !ok {
+		return "", false, nil
+	}
+
+	target, err := tools.SecureJoin(output, p)
+	return target, err == nil, err
+}
+
+// loadChecksums collects the expected checksums of image files by path.
+// Checksums passed explicitly are required to be verified, the ones from a file
+// only apply to the files that are copied
+func loadChecksums(opts *CopyOptions) (map[string]string, map[string]bool, error) {
+	checksums := make(map[string]string)
+	required := make(map[string]bool)
+
+	if opts.ChecksumsFile != "" {
+		f, err := os.Open(opts.ChecksumsFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open the checksums file: %w", err)
+		}
+		defer f.Close()
+
+		if checksums, err = tools.ParseChecksums(f); err != nil {
+			return nil, nil, fmt.Errorf("parse the checksums file '%s': %w", opts.ChecksumsFile, err)
+		}
+	}
+
+	for _, value := range opts.SHA256 {
+		name, hex, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			return nil, nil, fmt.Errorf("invalid checksum '%s', expected 'path=sha256'", value)
+		}
+
+		sum, err := tools.ParseChecksum(hex)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid checksum of '%s': %w", name, err)
+		}
+
+		p := tools.CleanPath(name)
+		checksums[p] = sum
+		required[p] = true
+	}
+
+	return checksums, required, nil
+}
+
+// expectedChecksum returns the expected checksum of a copied file by its image path or, like in
+// the SHA256SUMS files cp writes, by its target relative to the output directory
+func expectedChecksum(checksums map[string]string, p, target, output string, stream, checked bool) string {
+	if !checked {
+		return ""
+	}
+
+	if sum, ok := checksums[p]; ok || stream {
+		return sum
+	}
+
+	rel, err := filepath.Rel(checksumsDir(output), target)
+	if err != nil {
+		return ""
+	}
+
+	return checksums[filepath.ToSlash(rel)]
+}
+
+// checksumsDir returns the directory of the SHA256SUMS file, the output directory
+// or the directory of the output file
+func checksumsDir(output string) string {
+	if !tools.IsDirTarget(output) {
+		return filepath.Dir(output)
+	}
+
+	return output
+}
+
+// writeChecksumsFile writes the checksums of copied files to SHA256SUMS in the output directory,
+// with paths relative to it so the file can be checked with 'sha256sum -c'
+func writeChecksumsFile(output string, sums map[string]string) (string, error) {
+	dir := checksumsDir(output)
+
+	relative := make(map[string]string, len(sums))
+	for target, sum := range sums {
+		rel, err := filepath.Rel(dir, target)
+		if err != nil {
+			return "", fmt.Errorf("resolve '%s' relative to '%s': %w", target, dir, err)
+		}
+
+		relative[filepath.ToSlash(rel)] = sum
+	}
+
+	name := filepath.Join(dir, checksumsFileName)
+	f, err := os.Create(name)
+	if err != nil {
+		return "", fmt.Errorf("create the checksums file: %w", err)
+	}
+	defer f.Close()
+
+	if err = tools.WriteChecksums(f, relative); err != nil {
+		return "", fmt.Errorf("write the checksums file '%s': %w", name, err)
+	}
+
+	return name, nil
+}
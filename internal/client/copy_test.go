@@ -0,0 +1,376 @@
+package client
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ipaqsa/artship/internal/logs"
+)
+
+func TestCopyConflictingOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		opts    *CopyOptions
+		wantErr string
+	}{
+		{
+			name:    "emitted checksums on stdout",
+			output:  "-",
+			opts:    &CopyOptions{EmitChecksums: true},
+			wantErr: "checksums can not be emitted when streaming to stdout",
+		},
+		{
+			name:    "dependencies on stdout",
+			output:  "-",
+			opts:    &CopyOptions{WithDeps: true},
+			wantErr: "dependencies can not be copied when streaming to stdout",
+		},
+		{
+			name:    "dependencies of a single layer",
+			output:  t.TempDir(),
+			opts:    &CopyOptions{WithDeps: true, Layer: "0"},
+			wantErr: "dependencies can not be resolved in a single layer",
+		},
+	}
+
+	// The options are rejected before the image is pulled
+	c := New(&Options{Logger: logs.New(false)})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := c.Copy(context.Background(), "registry.invalid/image:tag", []string{"bin/sh"}, tt.output, tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Copy() error = %v, want '%s'", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseCopySpecs(t *testing.T) {
+	tests := []struct {
+		name      string
+		artifacts []string
+		output    string
+		regex     bool
+		// want lists 'source -> output' of every spec, mapped ones marked with '='
+		want    []string
+		wantErr string
+	}{
+		{
+			name:      "artifacts",
+			artifacts: []string{"bin/sh", "usr/lib/**/*.so*"},
+			output:    "./out",
+			want:      []string{"bin/sh -> ./out", "usr/lib/**/*.so* -> ./out"},
+		},
+		{
+			name:      "mappings",
+			artifacts: []string{"usr/sbin/nginx=./bin/nginx-1.25", "etc/nginx/=./conf/", "bin/sh"},
+			output:    "./out",
+			want:      []string{"usr/sbin/nginx = ./bin/nginx-1.25", "etc/nginx/ = ./conf/", "bin/sh -> ./out"},
+		},
+		{
+			name:      "several artifacts mapped into a directory",
+			artifacts: []string{"bin/sh=./bin/", "bin/ls=./bin/"},
+			output:    ".",
+			want:      []string{"bin/sh = ./bin/", "bin/ls = ./bin/"},
+		},
+		{
+			name:      "several artifacts mapped to a file",
+			artifacts: []string{"bin/sh=./bin/tool", "bin/ls=bin/tool"},
+			output:    ".",
+			wantErr:   "artifacts 'bin/sh' and 'bin/ls' are both mapped to the file 'bin/tool'",
+		},
+		{
+			name:      "missing source",
+			artifacts: []string{"=./bin/sh"},
+			output:    ".",
+			wantErr:   "invalid artifact mapping '=./bin/sh'",
+		},
+		{
+			name:      "missing destination",
+			artifacts: []string{"bin/sh="},
+			output:    ".",
+			wantErr:   "invalid artifact mapping 'bin/sh='",
+		},
+		{
+			name:      "mapping when streaming",
+			artifacts: []string{"bin/sh=./sh"},
+			output:    "-",
+			wantErr:   "artifact mappings can not be used when streaming to stdout",
+		},
+		{
+			name:      "duplicate source",
+			artifacts: []string{"bin/sh", "bin/sh=./sh"},
+			output:    ".",
+			wantErr:   "artifact 'bin/sh' is requested more than once",
+		},
+		{
+			name:      "invalid glob",
+			artifacts: []string{"lib[0-9=./lib/"},
+			output:    ".",
+			wantErr:   "unterminated character class",
+		},
+		{
+			name:      "invalid regular expression",
+			artifacts: []string{"^bin/(sh"},
+			output:    ".",
+			regex:     true,
+			wantErr:   "invalid regular expression",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specs, err := parseCopySpecs(tt.artifacts, tt.output, tt.regex)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseCopySpecs() error = %v, want '%s'", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, spec := range specs {
+				sep := "->"
+				if spec.mapped {
+					sep = "="
+				}
+				got = append(got, spec.source+" "+sep+" "+spec.output)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("parsed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopySpecTarget(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "existing"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		artifact string
+		opts     CopyOptions
+		// files maps image paths to their targets relative to the directory
+		files map[string]string
+	}{
+		{
+			name:     "directory renamed",
+			artifact: "etc/nginx=$DIR/conf",
+			files:    map[string]string{"etc/nginx": "conf", "etc/nginx/conf.d/default.conf": "conf/conf.d/default.conf"},
+		},
+		{
+			name:     "directory into a destination with a trailing slash",
+			artifact: "etc/nginx=$DIR/conf/",
+			files:    map[string]string{"etc/nginx": "conf/nginx", "etc/nginx/nginx.conf": "conf/nginx/nginx.conf"},
+		},
+		{
+			name:     "directory contents with a trailing slash on the source",
+			artifact: "etc/nginx/=$DIR/conf/",
+			files:    map[string]string{"etc/nginx/nginx.conf": "conf/nginx.conf"},
+		},
+		{
+			name:     "file renamed",
+			artifact: "usr/sbin/nginx=$DIR/bin/nginx-1.25",
+			files:    map[string]string{"usr/sbin/nginx": "bin/nginx-1.25"},
+		},
+		{
+			name:     "file into an existing directory",
+			artifact: "usr/sbin/nginx=$DIR/existing",
+			files:    map[string]string{"usr/sbin/nginx": "existing/nginx"},
+		},
+		{
+			name:     "file matched by its name",
+			artifact: "nginx=$DIR/bin/",
+			files:    map[string]string{"usr/sbin/nginx": "bin/nginx"},
+		},
+		{
+			name:     "glob into a directory",
+			artifact: "usr/lib/**/*.so=$DIR/libs/",
+			files:    map[string]string{"usr/lib/libfoo.so": "libs/libfoo.so", "usr/lib/x86_64/libbar.so": "libs/libbar.so"},
+		},
+		{
+			name:     "glob into a directory with image paths",
+			artifact: "usr/lib/**/*.so=$DIR/libs/",
+			opts:     CopyOptions{PreservePaths: true},
+			files:    map[string]string{"usr/lib/x86_64/libbar.so": "libs/usr/lib/x86_64/libbar.so"},
+		},
+		{
+			name:     "glob into a directory with stripped components",
+			artifact: "usr/lib/**/*.so=$DIR/libs/",
+			opts:     CopyOptions{StripComponents: 2},
+			files:    map[string]string{"usr/lib/x86_64/libbar.so": "libs/x86_64/libbar.so"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			artifact := strings.ReplaceAll(tt.artifact, "$DIR", dir)
+			specs, err := parseCopySpecs([]string{artifact}, ".", false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for name, want := range tt.files {
+				if !specs[0].matcher.Match(name) {
+					t.Errorf("'%s' does not match '%s'", name, tt.artifact)
+					continue
+				}
+
+				got, ok, err := specs[0].target(name, &tt.opts)
+				if err != nil || !ok {
+					t.Errorf("target(%s) = %v, %v", name, ok, err)
+					continue
+				}
+
+				if want = filepath.Join(dir, filepath.FromSlash(want)); got != want {
+					t.Errorf("target(%s) = %s, want %s", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadChecksums(t *testing.T) {
+	server, config := strings.Repeat("a", 64), strings.Repeat("b", 64)
+
+	file := filepath.Join(t.TempDir(), "SHA256SUMS")
+	if err := os.WriteFile(file, []byte(server+"  app/bin/server\n"+config+"  ./app/config.yaml\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	checksums, required, err := loadChecksums(&CopyOptions{
+		ChecksumsFile: file,
+		SHA256:        []string{"/app/bin/server=" + strings.ToUpper(config)},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Explicit checksums replace the ones of the file and must be verified
+	if want := map[string]string{"app/bin/server": config, "app/config.yaml": config}; !maps.Equal(checksums, want) {
+		t.Errorf("checksums %v, want %v", checksums, want)
+	}
+	if want := map[string]bool{"app/bin/server": true}; !maps.Equal(required, want) {
+		t.Errorf("required %v, want %v", required, want)
+	}
+
+	for _, value := range []string{"app/bin/server", "=" + server, "app/bin/server=abc"} {
+		if _, _, err = loadChecksums(&CopyOptions{SHA256: []string{value}}); err == nil {
+			t.Errorf("loadChecksums(%s) accepted an invalid checksum", value)
+		}
+	}
+}
+
+func TestExpectedChecksum(t *testing.T) {
+	dir := t.TempDir()
+	sum := strings.Repeat("c", 64)
+
+	tests := []struct {
+		name      string
+		checksums map[string]string
+		target    string
+		output    string
+		stream    bool
+		checked   bool
+		want      string
+	}{
+		{
+			name:      "image path",
+			checksums: map[string]string{"app/bin/server": sum},
+			target:    filepath.Join(dir, "server"),
+			output:    dir,
+			checked:   true,
+			want:      sum,
+		},
+		{
+			name:      "path relative to the output directory",
+			checksums: map[string]string{"bin/server": sum},
+			target:    filepath.Join(dir, "bin", "server"),
+			output:    dir,
+			checked:   true,
+			want:      sum,
+		},
+		{
+			name:      "path relative to the directory of the output file",
+			checksums: map[string]string{"server-1.0": sum},
+			target:    filepath.Join(dir, "server-1.0"),
+			output:    filepath.Join(dir, "server-1.0"),
+			checked:   true,
+			want:      sum,
+		},
+		{
+			name:      "streams match only image paths",
+			checksums: map[string]string{"app/bin/server": sum, "server": strings.Repeat("d", 64)},
+			target:    "app/bin/server",
+			output:    "-",
+			stream:    true,
+			checked:   true,
+			want:      sum,
+		},
+		{
+			name:      "no checksum",
+			checksums: map[string]string{"app/bin/client": sum},
+			target:    filepath.Join(dir, "server"),
+			output:    dir,
+			checked:   true,
+		},
+		{
+			name:      "entries without content",
+			checksums: map[string]string{"app/bin/server": sum},
+			target:    filepath.Join(dir, "server"),
+			output:    dir,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := expectedChecksum(tt.checksums, "app/bin/server", tt.target, tt.output, tt.stream, tt.checked)
+			if got != tt.want {
+				t.Errorf("expectedChecksum() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChecksumsFileRoundTrip(t *testing.T) {
+	// cp -a app/bin -o <output>/ --emit-checksums writes paths relative to the output
+	output := t.TempDir() + "/"
+	sums := map[string]string{
+		filepath.Join(output, "server"):             strings.Repeat("a", 64),
+		filepath.Join(output, "plugins", "auth.so"): strings.Repeat("b", 64),
+	}
+
+	name, err := writeChecksumsFile(output, sums)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checksums, _, err := loadChecksums(&CopyOptions{ChecksumsFile: name})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := map[string]string{"server": sums[filepath.Join(output, "server")], "plugins/auth.so": sums[filepath.Join(output, "plugins", "auth.so")]}; !maps.Equal(checksums, want) {
+		t.Errorf("checksums %v, want %v", checksums, want)
+	}
+
+	// The next copy with the same artifacts finds every file by its target
+	for target, sum := range sums {
+		if got := expectedChecksum(checksums, "app/bin/"+filepath.Base(target), target, output, false, true); got != sum {
+			t.Errorf("expected checksum of '%s' is %q, want %q", target, got, sum)
+		}
+	}
+}
@@ -0,0 +1,369 @@
+package client
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"debug/elf"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ipaqsa/artship/internal/logs"
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+const ldConfigPath = "etc/ld.so.conf"
+
+// depsIndex holds everything needed to resolve shared library dependencies of image files
+type depsIndex struct {
+	tree    *tools.FileTree
+	objects map[string]*tools.ELFInfo
+	configs map[string][]byte
+	roots   []string
+	ldDirs  []string
+}
+
+// copyWithDeps copies the artifacts together with the closure of their shared library
+// dependencies, preserving the image layout inside the output directory
+func (c *Client) copyWithDeps(ctx context.Context, imageRef string, matchers []*tools.Matcher, output string, limits *tools.Limits, strict bool) error {
+	c.logger.Debug("Indexing the image filesystem...")
+	idx, err := c.indexDeps(ctx, imageRef, matchers)
+	if err != nil {
+		return err
+	}
+
+	if len(idx.roots) == 0 {
+		return fmt.Errorf("no artifacts found in the image")
+	}
+
+	files, missing := idx.closure()
+	for _, lib := range missing {
+		c.logger.Warn("Could not resolve the shared library %s", lib)
+	}
+
+	c.logger.Debug("Resolved %d files to copy (%d requested)", len(files), len(idx.roots))
+
+	img, err := c.extractImage(ctx, imageRef)
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+
+	var copied int
+	var created tools.CreatedPaths
+	limiter := tools.NewLimiter(limits)
+	err = tools.WalkTar(img, func(r io.Reader, header *tar.Header) error {
+		name := tools.CleanPath(header.Name)
+		if _, ok := files[name]; !ok {
+			return nil
+		}
+
+		if err = limiter.Check(header); err != nil {
+			return err
+		}
+
+		// Parents are resolved inside the output, so symlinks copied before never lead outside
+		target, err := tools.LayoutTarget(output, header, strict)
+		if err != nil {
+			return err
+		}
+
+		created.Track(target)
+		if err = tools.CopyLayoutEntry(r, header, output, target); err != nil {
+			return fmt.Errorf("copy the artifact '%s': %w", name, err)
+		}
+
+		copied++
+		c.logger.Info(logs.Green("✓")+" Copied: %s", logs.Blue(header.Name))
+
+		if copied == len(files) {
+			return tools.ErrStopWalk
+		}
+
+		return nil
+	})
+	if err != nil {
+		if cleanupErr := created.Remove(); cleanupErr != nil {
+			c.logger.Warn("Could not remove the partial output: %v", cleanupErr)
+		}
+
+		return fmt.Errorf("walk image: %w", err)
+	}
+
+	c.logger.Info("%s", logs.BoldGreen(fmt.Sprintf("✓ Successfully copied %d files (%d artifacts with dependencies)", copied, len(idx.roots))))
+
+	return nil
+}
+
+// indexDeps walks the image and collects the file tree, ELF objects and dynamic linker configs
+func (c *Client) indexDeps(ctx context.Context, imageRef string, matchers []*tools.Matcher) (*depsIndex, error) {
+	img, err := c.extractImage(ctx, imageRef)
+	if err != nil {
+		return nil, err
+	}
+	defer img.Close()
+
+	idx := &depsIndex{
+		tree:    tools.NewFileTree(),
+		objects: make(map[string]*tools.ELFInfo),
+		configs: make(map[string][]byte),
+	}
+
+	err = tools.WalkTar(img, func(r io.Reader, header *tar.Header) error {
+		idx.tree.Add(header)
+
+		name := tools.CleanPath(header.Name)
+		root := tools.MatchAny(matchers, header.Name)
+		if root {
+			idx.roots = append(idx.roots, name)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		if isLdConfig(name) {
+			content, err := io.ReadAll(r)
+			if err != nil {
+				return fmt.Errorf("read '%s': %w", name, err)
+			}
+
+			idx.configs[name] = content
+			return nil
+		}
+
+		// Only requested artifacts and shared objects are worth parsing
+		if !root && !strings.Contains(path.Base(name), ".so") {
+			return nil
+		}
+
+		info, err := readELFInfo(r)
+		if err != nil {
+			c.logger.Debug("Skipping %s: %v", name, err)
+			return nil
+		}
+
+		idx.objects[name] = info
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk image: %w", err)
+	}
+
+	idx.ldDirs = idx.parseLdConfig(ldConfigPath, make(map[string]bool))
+	for _, muslPath := range idx.tree.Glob("etc/ld-musl-*.path") {
+		idx.ldDirs = append(idx.ldDirs, idx.parseLdConfig(muslPath, make(map[string]bool))...)
+	}
+
+	return idx, nil
+}
+
+// isLdConfig checks if the path is a dynamic linker search path config (glibc or musl)
+func isLdConfig(name string) bool {
+	if name == ldConfigPath || strings.HasPrefix(name, ldConfigPath+".d/") {
+		return true
+	}
+
+	return strings.HasPrefix(name, "etc/ld-musl-") && strings.HasSuffix(name, ".path")
+}
+
+// readELFInfo reads dynamic linking information of an ELF object from the tar stream
+func readELFInfo(r io.Reader) (*tools.ELFInfo, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("read file header: %w", err)
+	}
+
+	if !tools.IsELF(magic) {
+		return nil, fmt.Errorf("not an ELF object")
+	}
+
+	// The ELF parser needs random access, the object goes to a temporary file instead of memory
+	tmp, err := tools.SpoolChecked(br, "")
+	if err != nil {
+		return nil, fmt.Errorf("read file content: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	return tools.ReadELF(tmp)
+}
+
+// parseLdConfig parses the ld.so.conf file and returns library directories, following includes
+func (idx *depsIndex) parseLdConfig(name string, seen map[string]bool) []string {
+	resolved, _, err := idx.tree.Resolve(name)
+	if err != nil || seen[resolved] {
+		return nil
+	}
+	seen[resolved] = true
+
+	var dirs []string
+	for _, line := range strings.Split(string(idx.configs[resolved]), "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ' ' || r == '\t' || r == ':' || r == ','
+		})
+		if len(fields) == 0 || fields[0] == "hwcap" {
+			continue
+		}
+
+		if fields[0] != "include" {
+			dirs = append(dirs, fields...)
+			continue
+		}
+
+		for _, pattern := range fields[1:] {
+			if !strings.HasPrefix(pattern, "/") {
+				pattern = path.Join(path.Dir("/"+name), pattern)
+			}
+
+			for _, included := range idx.tree.Glob(pattern) {
+				dirs = append(dirs, idx.parseLdConfig(included, seen)...)
+			}
+		}
+	}
+
+	return dirs
+}
+
+// closure returns all files needed to run the requested artifacts and the unresolved libraries
+func (idx *depsIndex) closure() (map[string]struct{}, []string) {
+	files := make(map[string]struct{})
+	processed := make(map[string]bool)
+
+	var queue []string
+	var missing []string
+
+	// add adds the path with the symlinks leading to it, and queues the ELF object behind it
+	add := func(p string) bool {
+		resolved, links, err := idx.tree.Resolve(p)
+		if err != nil {
+			return false
+		}
+
+		files[tools.CleanPath(p)] = struct{}{}
+		files[resolved] = struct{}{}
+		for _, link := range links {
+			files[link] = struct{}{}
+		}
+
+		object := idx.objectPath(resolved)
+		files[object] = struct{}{}
+		if _, ok := idx.objects[object]; ok && !processed[object] {
+			processed[object] = true
+			queue = append(queue, object)
+		}
+
+		return true
+	}
+
+	for _, root := range idx.roots {
+		add(root)
+	}
+
+	for len(queue) > 0 {
+		object := queue[0]
+		queue = queue[1:]
+
+		info := idx.objects[object]
+		if info.Interpreter != "" && !add(info.Interpreter) {
+			missing = append(missing, fmt.Sprintf("%s (interpreter of %s)", info.Interpreter, object))
+		}
+
+		for _, needed := range info.Needed {
+			lib, ok := idx.findLibrary(needed, object, info)
+			if !ok || !add(lib) {
+				missing = append(missing, fmt.Sprintf("%s (needed by %s)", needed, object))
+			}
+		}
+	}
+
+	sort.Strings(missing)
+
+	return files, missing
+}
+
+// objectPath returns the path holding the content of the file, following hardlinks
+func (idx *depsIndex) objectPath(p string) string {
+	header, ok := idx.tree.Get(p)
+	if ok && header.Typeflag == tar.TypeLink {
+		return tools.CleanPath(header.Linkname)
+	}
+
+	return p
+}
+
+// findLibrary searches the library the same way the dynamic linker does:
+// DT_RPATH (without DT_RUNPATH), DT_RUNPATH, ld.so.conf and the default directories
+func (idx *depsIndex) findLibrary(needed, object string, info *tools.ELFInfo) (string, bool) {
+	if strings.Contains(needed, "/") {
+		return needed, idx.isCompatible(needed, info)
+	}
+
+	var dirs []string
+	if len(info.RunPath) == 0 {
+		dirs = append(dirs, info.RPath...)
+	}
+	dirs = append(dirs, info.RunPath...)
+	dirs = append(dirs, idx.ldDirs...)
+	dirs = append(dirs, defaultLibDirs(info.Class)...)
+
+	for _, dir := range dirs {
+		candidate := path.Join(expandOrigin(dir, object, info.Class), needed)
+		if idx.isCompatible(candidate, info) {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// isCompatible checks if the candidate exists and can be loaded by the object
+func (idx *depsIndex) isCompatible(candidate string, info *tools.ELFInfo) bool {
+	resolved, _, err := idx.tree.Resolve(candidate)
+	if err != nil {
+		return false
+	}
+
+	header, _ := idx.tree.Get(resolved)
+	if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeLink {
+		return false
+	}
+
+	// Libraries with unknown format are accepted, the dynamic linker would try them too
+	lib, ok := idx.objects[idx.objectPath(resolved)]
+	return !ok || info.Compatible(lib)
+}
+
+// expandOrigin substitutes dynamic string tokens supported by the dynamic linker
+func expandOrigin(dir, object string, class elf.Class) string {
+	lib := "lib"
+	if class == elf.ELFCLASS64 {
+		lib = "lib64"
+	}
+
+	return strings.NewReplacer(
+		"${ORIGIN}", path.Dir("/"+object),
+		"$ORIGIN", path.Dir("/"+object),
+		"${LIB}", lib,
+		"$LIB", lib,
+	).Replace(dir)
+}
+
+// defaultLibDirs returns the trusted directories searched by the dynamic linker
+func defaultLibDirs(class elf.Class) []string {
+	if class == elf.ELFCLASS64 {
+		return []string{"/lib64", "/usr/lib64", "/lib", "/usr/lib", "/usr/local/lib"}
+	}
+
+	return []string{"/lib", "/usr/lib", "/usr/local/lib"}
+}
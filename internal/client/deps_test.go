@@ -0,0 +1,126 @@
+package client
+
+import (
+	"archive/tar"
+	"debug/elf"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+func TestDepsClosure(t *testing.T) {
+	x64 := func(needed ...string) *tools.ELFInfo {
+		return &tools.ELFInfo{Class: elf.ELFCLASS64, Machine: elf.EM_X86_64, Needed: needed}
+	}
+
+	app := x64("libfoo.so.1", "libbar.so", "libc.so.6", "libmissing.so")
+	app.Interpreter = "/lib64/ld-linux-x86-64.so.2"
+	app.RunPath = []string{"$ORIGIN/../lib/app"}
+
+	// RPATH is ignored when RUNPATH is set
+	app.RPath = []string{"/opt/rpath"}
+
+	objects := map[string]*tools.ELFInfo{
+		"bin/app":                    app,
+		"lib64/ld-linux-x86-64.so.2": x64(),
+		"lib/app/libfoo.so.1":        x64("libdep.so"),
+		"lib/libfoo.so.1":            x64(),
+		"opt/rpath/libbar.so":        x64(),
+		"opt/custom/lib/libbar.so.2": x64(),
+		"lib/libdep.so":              x64(),
+		"lib64/libc.so.6":            {Class: elf.ELFCLASS32, Machine: elf.EM_386},
+		"usr/lib/libc.so.6":          x64(),
+	}
+
+	configs := map[string]string{
+		"etc/ld.so.conf":               "include /etc/ld.so.conf.d/*.conf\n",
+		"etc/ld.so.conf.d/custom.conf": "# custom libraries\n/opt/custom/lib # the bar library\n",
+	}
+
+	idx := &depsIndex{
+		tree:    tools.NewFileTree(),
+		objects: objects,
+		configs: make(map[string][]byte),
+		roots:   []string{"bin/app"},
+	}
+	// add adds the entry to the tree together with its parent directories
+	add := func(header *tar.Header) {
+		for dir := path.Dir(header.Name); dir != "."; dir = path.Dir(dir) {
+			idx.tree.Add(&tar.Header{Name: dir, Typeflag: tar.TypeDir})
+		}
+		idx.tree.Add(header)
+	}
+
+	for name := range objects {
+		add(&tar.Header{Name: name, Typeflag: tar.TypeReg})
+	}
+	for name, content := range configs {
+		add(&tar.Header{Name: name, Typeflag: tar.TypeReg})
+		idx.configs[name] = []byte(content)
+	}
+	add(&tar.Header{Name: "opt/custom/lib/libbar.so", Typeflag: tar.TypeSymlink, Linkname: "libbar.so.2"})
+
+	idx.ldDirs = idx.parseLdConfig(ldConfigPath, make(map[string]bool))
+	if want := []string{"/opt/custom/lib"}; !slices.Equal(idx.ldDirs, want) {
+		t.Errorf("ld.so.conf directories %v, want %v", idx.ldDirs, want)
+	}
+
+	files, missing := idx.closure()
+
+	want := []string{
+		"bin/app",
+		"lib/app/libfoo.so.1",        // RUNPATH with $ORIGIN
+		"lib/libdep.so",              // a default directory, needed by libfoo
+		"lib64/ld-linux-x86-64.so.2", // the interpreter
+		"opt/custom/lib/libbar.so",   // ld.so.conf, with the symlink
+		"opt/custom/lib/libbar.so.2",
+		"usr/lib/libc.so.6", // the first compatible library of the default directories
+	}
+	if got := slices.Sorted(maps.Keys(files)); !slices.Equal(got, want) {
+		t.Errorf("closure %v, want %v", got, want)
+	}
+
+	if want := []string{"libmissing.so (needed by bin/app)"}; !slices.Equal(missing, want) {
+		t.Errorf("missing %v, want %v", missing, want)
+	}
+}
+
+func TestReadELFInfo(t *testing.T) {
+	// The test binary is an ELF object of the host
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err = elf.NewFile(f); err != nil {
+		t.Skipf("the test binary is not an ELF object: %v", err)
+	}
+	if _, err = f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := readELFInfo(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if info.Class == elf.ELFCLASSNONE || info.Machine == elf.EM_NONE {
+		t.Errorf("unexpected ELF info: %+v", info)
+	}
+
+	if _, err = readELFInfo(strings.NewReader("#!/bin/sh\necho hello\n")); err == nil {
+		t.Errorf("a shell script is read as an ELF object")
+	}
+}
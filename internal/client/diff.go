@@ -1,12 +1,20 @@
 package client
 
 import (
+	"archive/tar"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
+	"maps"
+	"regexp"
 	"sort"
 	"strings"
 
+	crv1 "github.com/google/go-containerregistry/pkg/v1"
+
 	"github.com/ipaqsa/artship/internal/logs"
 	"github.com/ipaqsa/artship/internal/tools"
 )
@@ -29,6 +37,34 @@ type FileInfo struct {
 	Mode string `json:"mode"`
 	Hash string `json:"hash,omitempty"` // SHA256 hash for content comparison
 	Type string `json:"type"`
+	// Link is the symlink or hardlink target
+	Link string `json:"link,omitempty"`
+	// Owner is the 'uid:gid' of the file
+	Owner string `json:"owner,omitempty"`
+}
+
+// Modification reasons of a file present in both images
+const (
+	ReasonType    = "type"
+	ReasonSize    = "size"
+	ReasonMode    = "mode"
+	ReasonContent = "content"
+	ReasonSymlink = "symlink"
+	ReasonOwner   = "owner"
+)
+
+// DiffOptions contains options for image comparison
+type DiffOptions struct {
+	// IncludeUnchanged adds unchanged files to the result
+	IncludeUnchanged bool
+	// Content compares SHA256 of regular files, not only their metadata
+	Content bool
+	// Patch adds a git-compatible patch of modified files, implies Content
+	Patch bool
+	// PatchPaths limits the patch to files matching the globs
+	PatchPaths []string
+	// Ignore excludes files matching the globs from the comparison
+	Ignore []string
 }
 
 // DiffEntry represents a single difference between images
@@ -40,6 +76,14 @@ type DiffEntry struct {
 	OldMode string     `json:"old_mode,omitempty"`
 	NewMode string     `json:"new_mode,omitempty"`
 	Type    string     `json:"type"`
+
+	OldHash  string   `json:"old_hash,omitempty"`
+	NewHash  string   `json:"new_hash,omitempty"`
+	OldLink  string   `json:"old_link,omitempty"`
+	NewLink  string   `json:"new_link,omitempty"`
+	OldOwner string   `json:"old_owner,omitempty"`
+	NewOwner string   `json:"new_owner,omitempty"`
+	Reasons  []string `json:"reasons,omitempty"` // Why a file is modified (size, mode, content, symlink, owner, type)
 }
 
 // DiffResult contains the comparison results
@@ -53,6 +97,9 @@ type DiffResult struct {
 	TotalAdded   int         `json:"total_added"`
 	TotalRemoved int         `json:"total_removed"`
 	TotalChanged int         `json:"total_changed"`
+	SourceSize   int64       `json:"source_size"` // Total size of regular files in the first image
+	Patch        string      `json:"patch,omitempty"`
+	Violations   []string    `json:"violations,omitempty"`
 }
 
 // String returns formatted diff output with colors
@@ -99,17 +146,7 @@ func (r *DiffResult) String(showUnchanged bool) string {
 	if len(r.Modified) > 0 {
 		sb.WriteString(logs.BoldYellow("Modified files:\n"))
 		for _, entry := range r.Modified {
-			var details string
-			if entry.OldSize != entry.NewSize {
-				details = fmt.Sprintf("(%s → %s)", tools.FormatSize(entry.OldSize), tools.FormatSize(entry.NewSize))
-			}
-			if entry.OldMode != entry.NewMode {
-				if details != "" {
-					details += fmt.Sprintf(", mode: %s → %s", entry.OldMode, entry.NewMode)
-				} else {
-					details = fmt.Sprintf("(mode: %s → %s)", entry.OldMode, entry.NewMode)
-				}
-			}
+			details := entry.changeDetails()
 			sb.WriteString(logs.FormatDiffLine(string(FileStatusModified), entry.Path, details))
 			sb.WriteString("\n")
 		}
@@ -129,6 +166,33 @@ func (r *DiffResult) String(showUnchanged bool) string {
 	return sb.String()
 }
 
+// changeDetails describes what changed in a modified file
+func (e *DiffEntry) changeDetails() string {
+	var parts []string
+	for _, reason := range e.Reasons {
+		switch reason {
+		case ReasonType:
+			parts = append(parts, "type changed")
+		case ReasonSize:
+			parts = append(parts, fmt.Sprintf("%s → %s", tools.FormatSize(e.OldSize), tools.FormatSize(e.NewSize)))
+		case ReasonMode:
+			parts = append(parts, fmt.Sprintf("mode: %s → %s", e.OldMode, e.NewMode))
+		case ReasonSymlink:
+			parts = append(parts, fmt.Sprintf("link: %s → %s", e.OldLink, e.NewLink))
+		case ReasonOwner:
+			parts = append(parts, fmt.Sprintf("owner: %s → %s", e.OldOwner, e.NewOwner))
+		case ReasonContent:
+			parts = append(parts, "content changed")
+		}
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
 // ToJSON returns JSON representation of the diff result
 func (r *DiffResult) ToJSON() (string, error) {
 	data, err := json.MarshalIndent(r, "", "  ")
@@ -139,23 +203,46 @@ func (r *DiffResult) ToJSON() (string, error) {
 }
 
 // Diff compares two images and returns the differences
-func (c *Client) Diff(ctx context.Context, image1Ref, image2Ref string, includeUnchanged bool) (*DiffResult, error) {
+func (c *Client) Diff(ctx context.Context, image1Ref, image2Ref string, opts *DiffOptions) (*DiffResult, error) {
 	if image1Ref == "" || image2Ref == "" {
 		return nil, fmt.Errorf("both image references must be provided")
 	}
 
+	if opts == nil {
+		opts = &DiffOptions{}
+	}
+
+	ignore, err := compileGlobs(opts.Ignore)
+	if err != nil {
+		return nil, fmt.Errorf("compile ignore patterns: %w", err)
+	}
+
 	c.logger.Info("Analyzing %s...", image1Ref)
-	sourceFiles, err := c.getFileMap(ctx, image1Ref)
+	source, err := c.files(ctx, image1Ref)
 	if err != nil {
 		return nil, fmt.Errorf("get files from %s: %w", image1Ref, err)
 	}
 
 	c.logger.Info("Analyzing %s...", image2Ref)
-	targetFiles, err := c.getFileMap(ctx, image2Ref)
+	target, err := c.files(ctx, image2Ref)
 	if err != nil {
 		return nil, fmt.Errorf("get files from %s: %w", image2Ref, err)
 	}
 
+	// Hardlinks are regular files on disk, so they are compared by their targets
+	if source.root != "" || target.root != "" {
+		source.resolveHardlinks()
+		target.resolveHardlinks()
+	}
+
+	if opts.Content || opts.Patch {
+		if err = c.hashCommonFiles(source, target, ignore); err != nil {
+			return nil, err
+		}
+	}
+
+	sourceFiles, targetFiles := source.files, target.files
+
 	c.logger.Debug("Comparing %d files from source with %d files from target", len(sourceFiles), len(targetFiles))
 
 	result := &DiffResult{
@@ -166,33 +253,49 @@ func (c *Client) Diff(ctx context.Context, image1Ref, image2Ref string, includeU
 		Modified:    []DiffEntry{},
 	}
 
-	if includeUnchanged {
+	if opts.IncludeUnchanged {
 		result.Unchanged = []DiffEntry{}
 	}
 
 	// Find removed and modified files
 	for path, sourceInfo := range sourceFiles {
+		if matchAnyRegexp(ignore, path) {
+			continue
+		}
+
+		if sourceInfo.Type == "file" {
+			result.SourceSize += sourceInfo.Size
+		}
+
 		if targetInfo, exists := targetFiles[path]; exists {
 			// File exists in both images
-			if sourceInfo.Size != targetInfo.Size || sourceInfo.Mode != targetInfo.Mode {
+			if reasons := compareFiles(sourceInfo, targetInfo); len(reasons) > 0 {
 				// File was modified
 				result.Modified = append(result.Modified, DiffEntry{
-					Path:    path,
-					Status:  FileStatusModified,
-					OldSize: sourceInfo.Size,
-					NewSize: targetInfo.Size,
-					OldMode: sourceInfo.Mode,
-					NewMode: targetInfo.Mode,
-					Type:    targetInfo.Type,
+					Path:     path,
+					Status:   FileStatusModified,
+					OldSize:  sourceInfo.Size,
+					NewSize:  targetInfo.Size,
+					OldMode:  sourceInfo.Mode,
+					NewMode:  targetInfo.Mode,
+					OldHash:  sourceInfo.Hash,
+					NewHash:  targetInfo.Hash,
+					OldLink:  sourceInfo.Link,
+					NewLink:  targetInfo.Link,
+					OldOwner: sourceInfo.Owner,
+					NewOwner: targetInfo.Owner,
+					Type:     targetInfo.Type,
+					Reasons:  reasons,
 				})
 				result.TotalChanged++
-			} else if includeUnchanged {
+			} else if opts.IncludeUnchanged {
 				// File is unchanged
 				result.Unchanged = append(result.Unchanged, DiffEntry{
 					Path:    path,
 					Status:  FileStatusUnchanged,
 					NewSize: targetInfo.Size,
 					NewMode: targetInfo.Mode,
+					NewHash: targetInfo.Hash,
 					Type:    targetInfo.Type,
 				})
 			}
@@ -203,6 +306,7 @@ func (c *Client) Diff(ctx context.Context, image1Ref, image2Ref string, includeU
 				Status:  FileStatusRemoved,
 				OldSize: sourceInfo.Size,
 				OldMode: sourceInfo.Mode,
+				OldLink: sourceInfo.Link,
 				Type:    sourceInfo.Type,
 			})
 			result.TotalRemoved++
@@ -211,13 +315,14 @@ func (c *Client) Diff(ctx context.Context, image1Ref, image2Ref string, includeU
 
 	// Find added files
 	for path, targetInfo := range targetFiles {
-		if _, exists := sourceFiles[path]; !exists {
+		if _, exists := sourceFiles[path]; !exists && !matchAnyRegexp(ignore, path) {
 			// File was added
 			result.Added = append(result.Added, DiffEntry{
 				Path:    path,
 				Status:  FileStatusAdded,
 				NewSize: targetInfo.Size,
 				NewMode: targetInfo.Mode,
+				NewLink: targetInfo.Link,
 				Type:    targetInfo.Type,
 			})
 			result.TotalAdded++
@@ -228,34 +333,254 @@ func (c *Client) Diff(ctx context.Context, image1Ref, image2Ref string, includeU
 	sort.Slice(result.Added, func(i, j int) bool { return result.Added[i].Path < result.Added[j].Path })
 	sort.Slice(result.Removed, func(i, j int) bool { return result.Removed[i].Path < result.Removed[j].Path })
 	sort.Slice(result.Modified, func(i, j int) bool { return result.Modified[i].Path < result.Modified[j].Path })
-	if includeUnchanged {
+	if opts.IncludeUnchanged {
 		sort.Slice(result.Unchanged, func(i, j int) bool { return result.Unchanged[i].Path < result.Unchanged[j].Path })
 	}
 
+	if opts.Patch {
+		if result.Patch, err = c.buildPatch(source, target, result.Modified, opts.PatchPaths); err != nil {
+			return nil, fmt.Errorf("build patch: %w", err)
+		}
+	}
+
 	return result, nil
 }
 
-// getFileMap returns a map of file paths to file info for an image
-func (c *Client) getFileMap(ctx context.Context, imageRef string) (map[string]*FileInfo, error) {
-	artifacts, err := c.List(ctx, imageRef, "all", "")
+// compareFiles returns the reasons why the file differs, empty if it is unchanged.
+// Content and owner are compared only when both sides know them
+func compareFiles(old, new *FileInfo) []string {
+	var reasons []string
+
+	if old.Type != new.Type {
+		reasons = append(reasons, ReasonType)
+	}
+	if old.Size != new.Size {
+		reasons = append(reasons, ReasonSize)
+	}
+	if old.Mode != new.Mode {
+		reasons = append(reasons, ReasonMode)
+	}
+	if old.Link != new.Link {
+		reasons = append(reasons, ReasonSymlink)
+	}
+	if old.Owner != "" && new.Owner != "" && old.Owner != new.Owner {
+		reasons = append(reasons, ReasonOwner)
+	}
+	if old.Hash != "" && new.Hash != "" && old.Hash != new.Hash {
+		reasons = append(reasons, ReasonContent)
+	}
+
+	return reasons
+}
+
+// fileSet is the merged filesystem of an image with the layer providing each file,
+// or the files of a local directory
+type fileSet struct {
+	layers  []crv1.Layer
+	digests []string
+	files   map[string]*FileInfo
+	origin  map[string]int    // Index of the layer providing the file
+	root    string            // Local directory the files are read from
+	links   map[string]string // Resolved hardlinks and their targets
+}
+
+// imageFiles reads the image layers one by one and merges them, applying whiteouts
+func (c *Client) imageFiles(ctx context.Context, imageRef string) (*fileSet, error) {
+	layers, err := c.imageLayers(ctx, imageRef)
 	if err != nil {
 		return nil, err
 	}
 
-	fileMap := make(map[string]*FileInfo)
-	for _, artifact := range artifacts {
-		// Skip directories for diff (only compare files)
-		if artifact.Type == "dir" {
+	set := &fileSet{
+		layers:  layers,
+		digests: make([]string, len(layers)),
+		files:   make(map[string]*FileInfo),
+		origin:  make(map[string]int),
+	}
+
+	for i, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("get layer digest: %w", err)
+		}
+		set.digests[i] = digest.String()
+
+		rc, err := layer.Uncompressed()
+		if err != nil {
+			return nil, fmt.Errorf("read the layer '%s': %w", digest, err)
+		}
+
+		changes, err := tools.ReadLayer(rc, nil)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read the layer '%s': %w", digest, err)
+		}
+
+		// Whiteouts apply to the lower layers only
+		tools.RemoveHidden(set.files, changes, func(p string, _ *FileInfo) {
+			delete(set.origin, p)
+		})
+
+		for p, header := range changes.Entries {
+			// Skip directories for diff (only compare files)
+			if header.Typeflag == tar.TypeDir {
+				delete(set.files, p)
+				delete(set.origin, p)
+				continue
+			}
+
+			set.files[p] = newFileInfo(p, header)
+			set.origin[p] = i
+		}
+	}
+
+	return set, nil
+}
+
+// hashCommonFiles computes SHA256 of regular files present in both images and not ignored.
+// Files provided by the same layer in both images are identical and are not read
+func (c *Client) hashCommonFiles(source, target *fileSet, ignore []*regexp.Regexp) error {
+	sourcePaths := make(map[int]map[string]bool)
+	targetPaths := make(map[int]map[string]bool)
+
+	var shared int
+	for p, sourceInfo := range source.files {
+		targetInfo, ok := target.files[p]
+		if !ok || sourceInfo.Type != "file" || targetInfo.Type != "file" || matchAnyRegexp(ignore, p) {
+			continue
+		}
+
+		if source.sameOrigin(target, p) {
+			shared++
+			continue
+		}
+
+		source.addPath(sourcePaths, p)
+		target.addPath(targetPaths, p)
+	}
+
+	c.logger.Debug("Skipping content comparison of %d files from shared layers", shared)
+
+	if err := c.hashFiles(source, sourcePaths); err != nil {
+		return err
+	}
+
+	return c.hashFiles(target, targetPaths)
+}
+
+// sameOrigin checks if the file comes from the same layer in both images
+func (s *fileSet) sameOrigin(other *fileSet, p string) bool {
+	if s.root != "" || other.root != "" {
+		return false
+	}
+
+	return s.digests[s.origin[p]] == other.digests[other.origin[p]]
+}
+
+// addPath adds the file to the paths to read, grouped by the providing layer
+func (s *fileSet) addPath(paths map[int]map[string]bool, p string) {
+	p = s.realPath(p)
+	addLayerPath(paths, s.origin[p], p)
+}
+
+// realPath returns the path the file content is stored at
+func (s *fileSet) realPath(p string) string {
+	if target, ok := s.links[p]; ok {
+		return target
+	}
+
+	return p
+}
+
+// hashFiles fills SHA256 hashes of the requested files
+func (c *Client) hashFiles(set *fileSet, paths map[int]map[string]bool) error {
+	err := c.readFiles(set, paths, func(p string, r io.Reader) error {
+		hash := sha256.New()
+		if _, err := io.Copy(hash, r); err != nil {
+			return fmt.Errorf("read '%s': %w", p, err)
+		}
+		set.files[p].Hash = hex.EncodeToString(hash.Sum(nil))
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for link, target := range set.links {
+		set.files[link].Hash = set.files[target].Hash
+	}
+
+	return nil
+}
+
+// readFiles reads only the layers providing the requested regular files and calls f for each of them
+func (c *Client) readFiles(set *fileSet, paths map[int]map[string]bool, f func(p string, r io.Reader) error) error {
+	if set.root != "" {
+		return readDirFiles(set.root, paths, f)
+	}
+
+	for i, layer := range set.layers {
+		want := paths[i]
+		if len(want) == 0 {
 			continue
 		}
 
-		fileMap[artifact.Path] = &FileInfo{
-			Path: artifact.Path,
-			Size: artifact.Size,
-			Mode: artifact.Mode,
-			Type: artifact.Type,
+		c.logger.Debug("Reading %d files from layer %s", len(want), set.digests[i])
+
+		rc, err := layer.Uncompressed()
+		if err != nil {
+			return fmt.Errorf("read the layer '%s': %w", set.digests[i], err)
+		}
+
+		pending := maps.Clone(want)
+		_, err = tools.ReadLayer(rc, func(r io.Reader, header *tar.Header) error {
+			p := tools.CleanPath(header.Name)
+			if !pending[p] || header.Typeflag != tar.TypeReg {
+				return nil
+			}
+
+			if err := f(p, r); err != nil {
+				return err
+			}
+
+			if delete(pending, p); len(pending) == 0 {
+				return tools.ErrStopWalk
+			}
+
+			return nil
+		})
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("read files of the layer '%s': %w", set.digests[i], err)
 		}
 	}
 
-	return fileMap, nil
+	return nil
+}
+
+// addLayerPath adds the path to the set of paths of the layer
+func addLayerPath(paths map[int]map[string]bool, layer int, p string) {
+	if paths[layer] == nil {
+		paths[layer] = make(map[string]bool)
+	}
+	paths[layer][p] = true
+}
+
+// newFileInfo creates file info from the tar header.
+// The mode keeps only the bits tarMode sets, some images also store the file type in it
+func newFileInfo(p string, header *tar.Header) *FileInfo {
+	info := &FileInfo{
+		Path:  p,
+		Size:  header.Size,
+		Mode:  fmt.Sprintf("%04o", header.Mode&07777),
+		Type:  tools.GetArtifactType(header.Typeflag),
+		Owner: fmt.Sprintf("%d:%d", header.Uid, header.Gid),
+	}
+
+	if header.Typeflag == tar.TypeSymlink || header.Typeflag == tar.TypeLink {
+		info.Link = header.Linkname
+	}
+
+	return info
 }
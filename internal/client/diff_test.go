@@ -0,0 +1,37 @@
+package client
+
+import (
+	"archive/tar"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestNewFileInfoMode(t *testing.T) {
+	tests := []struct {
+		name string
+		mode int64
+		want string
+	}{
+		{name: "permission bits", mode: 0o644, want: "0644"},
+		{name: "file type bits of a regular file", mode: 0o100644, want: "0644"},
+		{name: "file type bits of a directory", mode: 0o40755, want: "0755"},
+		{name: "setuid", mode: 0o104755, want: "4755"},
+		{name: "sticky directory", mode: 0o41777, want: "1777"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := newFileInfo("p", &tar.Header{Name: "p", Typeflag: tar.TypeReg, Mode: tt.mode})
+			if info.Mode != tt.want {
+				t.Errorf("mode %o is shown as %s, want %s", tt.mode, info.Mode, tt.want)
+			}
+		})
+	}
+
+	// The image side matches the same file read from a directory, as dirFiles formats it
+	image := newFileInfo("p", &tar.Header{Typeflag: tar.TypeReg, Mode: 0o104755})
+	if dir := fmt.Sprintf("%04o", tarMode(fs.ModeSetuid|0o755)); image.Mode != dir {
+		t.Errorf("the image mode %s differs from the directory mode %s", image.Mode, dir)
+	}
+}
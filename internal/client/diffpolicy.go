@@ -0,0 +1,60 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+// DiffPolicy defines which differences between images are not allowed
+type DiffPolicy struct {
+	// FailOn lists the file statuses that violate the policy
+	FailOn []FileStatus
+	// MaxSizeIncrease is the allowed growth of the files in bytes, zero means no limit
+	MaxSizeIncrease int64
+	// MaxSizeIncreasePercent is the allowed growth relative to the first image, zero means no limit
+	MaxSizeIncreasePercent float64
+}
+
+// HasChanges checks if the result contains any added, removed or modified files
+func (r *DiffResult) HasChanges() bool {
+	return len(r.Added)+len(r.Removed)+len(r.Modified) > 0
+}
+
+// CheckPolicy records and returns the policy violations of the result
+func (r *DiffResult) CheckPolicy(policy *DiffPolicy) []string {
+	var violations []string
+
+	for _, status := range policy.FailOn {
+		var count int
+		switch status {
+		case FileStatusAdded:
+			count = len(r.Added)
+		case FileStatusRemoved:
+			count = len(r.Removed)
+		case FileStatusModified:
+			count = len(r.Modified)
+		}
+
+		if count > 0 {
+			violations = append(violations, fmt.Sprintf("%d files %s", count, status))
+		}
+	}
+
+	delta := r.SizeDelta()
+	if policy.MaxSizeIncrease > 0 && delta > policy.MaxSizeIncrease {
+		violations = append(violations, fmt.Sprintf("size increased by %s, limit is %s",
+			tools.FormatSize(delta), tools.FormatSize(policy.MaxSizeIncrease)))
+	}
+
+	if policy.MaxSizeIncreasePercent > 0 && delta > 0 {
+		percent := float64(delta) * 100 / float64(max(r.SourceSize, 1))
+		if percent > policy.MaxSizeIncreasePercent {
+			violations = append(violations, fmt.Sprintf("size increased by %s (%.1f%%), limit is %g%%",
+				tools.FormatSize(delta), percent, policy.MaxSizeIncreasePercent))
+		}
+	}
+
+	r.Violations = violations
+	return violations
+}
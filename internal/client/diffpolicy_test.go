@@ -0,0 +1,84 @@
+package client
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCheckPolicy(t *testing.T) {
+	result := &DiffResult{
+		SourceSize: 1000,
+		Added:      []DiffEntry{{Path: "usr/bin/tool", NewSize: 150}},
+		Modified:   []DiffEntry{{Path: "etc/app.conf", OldSize: 100, NewSize: 80}, {Path: "etc/hosts", OldMode: "0644", NewMode: "0600"}},
+	}
+
+	tests := []struct {
+		name   string
+		result *DiffResult
+		policy *DiffPolicy
+		want   []string
+	}{
+		{
+			name:   "empty policy",
+			result: result,
+			policy: &DiffPolicy{},
+		},
+		{
+			name:   "statuses with changes",
+			result: result,
+			policy: &DiffPolicy{FailOn: []FileStatus{FileStatusAdded, FileStatusRemoved, FileStatusModified}},
+			want:   []string{"1 files added", "2 files modified"},
+		},
+		{
+			name:   "statuses without changes",
+			result: result,
+			policy: &DiffPolicy{FailOn: []FileStatus{FileStatusRemoved}},
+		},
+		{
+			name:   "size increase above the limit",
+			result: result,
+			policy: &DiffPolicy{MaxSizeIncrease: 100},
+			want:   []string{"size increased by 130 B, limit is 100 B"},
+		},
+		{
+			name:   "size increase at the limit",
+			result: result,
+			policy: &DiffPolicy{MaxSizeIncrease: 130},
+		},
+		{
+			name:   "size increase above the percentage",
+			result: result,
+			policy: &DiffPolicy{MaxSizeIncreasePercent: 10},
+			want:   []string{"size increased by 130 B (13.0%), limit is 10%"},
+		},
+		{
+			name:   "size increase within the percentage",
+			result: result,
+			policy: &DiffPolicy{MaxSizeIncreasePercent: 15},
+		},
+		{
+			name:   "size decrease",
+			result: &DiffResult{SourceSize: 1000, Removed: []DiffEntry{{Path: "a", OldSize: 500}}},
+			policy: &DiffPolicy{MaxSizeIncrease: 1, MaxSizeIncreasePercent: 1},
+		},
+		{
+			name:   "growth of an empty image",
+			result: &DiffResult{Added: []DiffEntry{{Path: "a", NewSize: 10}}},
+			policy: &DiffPolicy{MaxSizeIncreasePercent: 50},
+			want:   []string{"size increased by 10 B (1000.0%), limit is 50%"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.result.CheckPolicy(tt.policy)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("CheckPolicy() = %q, want %q", got, tt.want)
+			}
+
+			if !slices.Equal(tt.result.Violations, tt.want) {
+				t.Errorf("recorded violations %q, want %q", tt.result.Violations, tt.want)
+			}
+		})
+	}
+}
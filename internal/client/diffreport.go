@@ -0,0 +1,360 @@
+package client
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+// maxTopChanges is the number of the largest size changes shown in reports
+const maxTopChanges = 10
+
+// SizeChange is the size difference of a single file
+type SizeChange struct {
+	Path   string     `json:"path"`
+	Status FileStatus `json:"status"`
+	Delta  int64      `json:"delta"`
+}
+
+// SizeDelta returns the total size difference of the changed files
+func (r *DiffResult) SizeDelta() int64 {
+	var delta int64
+	for _, change := range r.sizeChanges() {
+		delta += change.Delta
+	}
+
+	return delta
+}
+
+// TopSizeChanges returns the files with the largest size differences
+func (r *DiffResult) TopSizeChanges(limit int) []SizeChange {
+	changes := r.sizeChanges()
+	sort.SliceStable(changes, func(i, j int) bool {
+		return abs(changes[i].Delta) > abs(changes[j].Delta)
+	})
+
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+
+	return changes
+}
+
+// sizeChanges returns size differences of all changed files
+func (r *DiffResult) sizeChanges() []SizeChange {
+	var changes []SizeChange
+	for _, entry := range r.Added {
+		changes = append(changes, SizeChange{Path: entry.Path, Status: FileStatusAdded, Delta: entry.NewSize})
+	}
+	for _, entry := range r.Removed {
+		changes = append(changes, SizeChange{Path: entry.Path, Status: FileStatusRemoved, Delta: -entry.OldSize})
+	}
+	for _, entry := range r.Modified {
+		if entry.NewSize != entry.OldSize {
+			changes = append(changes, SizeChange{Path: entry.Path, Status: FileStatusModified, Delta: entry.NewSize - entry.OldSize})
+		}
+	}
+
+	return changes
+}
+
+// AddedExecutables returns added regular files with an executable bit
+func (r *DiffResult) AddedExecutables() []DiffEntry {
+	return executables(r.Added, func(e DiffEntry) string { return e.NewMode })
+}
+
+// RemovedExecutables returns removed regular files with an executable bit
+func (r *DiffResult) RemovedExecutables() []DiffEntry {
+	return executables(r.Removed, func(e DiffEntry) string { return e.OldMode })
+}
+
+// executables filters regular files with an executable bit
+func executables(entries []DiffEntry, mode func(DiffEntry) string) []DiffEntry {
+	var res []DiffEntry
+	for _, entry := range entries {
+		perm, err := strconv.ParseInt(mode(entry), 8, 64)
+		if err == nil && entry.Type == "file" && perm&0o111 != 0 {
+			res = append(res, entry)
+		}
+	}
+
+	return res
+}
+
+// ToMarkdown returns a Markdown report with collapsible sections, suitable for pull request comments
+func (r *DiffResult) ToMarkdown() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("### Image diff: %s → %s\n\n", markdownCode(r.SourceImage, false), markdownCode(r.TargetImage, false)))
+	sb.WriteString("| | Files |\n|---|---:|\n")
+	sb.WriteString(fmt.Sprintf("| ➕ Added | %d |\n", r.TotalAdded))
+	sb.WriteString(fmt.Sprintf("| ➖ Removed | %d |\n", r.TotalRemoved))
+	sb.WriteString(fmt.Sprintf("| ✏️ Modified | %d |\n\n", r.TotalChanged))
+	sb.WriteString(fmt.Sprintf("**Size change:** %s\n\n", formatDelta(r.SizeDelta())))
+
+	if len(r.Violations) > 0 {
+		sb.WriteString("**❌ Policy violated:**\n\n")
+		for _, violation := range r.Violations {
+			sb.WriteString(fmt.Sprintf("- %s\n", violation))
+		}
+		sb.WriteString("\n")
+	}
+
+	if top := r.TopSizeChanges(maxTopChanges); len(top) > 0 {
+		sb.WriteString("<details>\n<summary>Top size changes</summary>\n\n")
+		sb.WriteString("| Change | Path | Status |\n|---:|---|---|\n")
+		for _, change := range top {
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s |\n", formatDelta(change.Delta), markdownCode(change.Path, true), change.Status))
+		}
+		sb.WriteString("\n</details>\n\n")
+	}
+
+	writeMarkdownSection(&sb, "Added executables", r.AddedExecutables(), func(e DiffEntry) string {
+		return fmt.Sprintf("%s, %s", e.NewMode, tools.FormatSize(e.NewSize))
+	})
+	writeMarkdownSection(&sb, "Removed executables", r.RemovedExecutables(), func(e DiffEntry) string {
+		return fmt.Sprintf("%s, %s", e.OldMode, tools.FormatSize(e.OldSize))
+	})
+	writeMarkdownSection(&sb, "Added files", r.Added, func(e DiffEntry) string {
+		return fmt.Sprintf("%s, %s", e.Type, tools.FormatSize(e.NewSize))
+	})
+	writeMarkdownSection(&sb, "Removed files", r.Removed, func(e DiffEntry) string {
+		return fmt.Sprintf("%s, %s", e.Type, tools.FormatSize(e.OldSize))
+	})
+	writeMarkdownSection(&sb, "Modified files", r.Modified, func(e DiffEntry) string {
+		return strings.Trim(e.changeDetails(), "()")
+	})
+
+	return sb.String()
+}
+
+// writeMarkdownSection writes a collapsible list of entries
+func writeMarkdownSection(sb *strings.Builder, title string, entries []DiffEntry, details func(DiffEntry) string) {
+	if len(entries) == 0 {
+		return
+	}
+
+	sb.WriteString(fmt.Sprintf("<details>\n<summary>%s (%d)</summary>\n\n", title, len(entries)))
+	for _, entry := range entries {
+		sb.WriteString(fmt.Sprintf("- %s (%s)\n", markdownCode(entry.Path, false), details(entry)))
+	}
+	sb.WriteString("\n</details>\n\n")
+}
+
+// markdownCode formats the text as a code span. Backslash escapes are literal inside code spans,
+// so backticks are kept with a longer fence and only '|' is escaped, for the table parser
+func markdownCode(s string, table bool) string {
+	if table {
+		s = strings.ReplaceAll(s, "|", "\\|")
+	}
+
+	fence := "`"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+
+	// A backtick next to the fence would extend it, the padding spaces are stripped
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+
+	return fence + s + fence
+}
+
+var htmlReport = template.Must(template.New("diff").Funcs(template.FuncMap{
+	"size":    tools.FormatSize,
+	"delta":   formatDelta,
+	"details": func(e DiffEntry) string { return strings.Trim(e.changeDetails(), "()") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Image diff: {{.SourceImage}} → {{.TargetImage}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { border: 1px solid #ddd; padding: 4px 8px; text-align: left; }
+code { font-size: 0.9em; }
+summary { cursor: pointer; font-weight: bold; margin: 0.5em 0; }
+.added { color: #1a7f37; } .removed { color: #cf222e; } .modified { color: #9a6700; }
+</style>
+</head>
+<body>
+<h2>Image diff: <code>{{.SourceImage}}</code> → <code>{{.TargetImage}}</code></h2>
+<table>
+<tr><td class="added">Added</td><td>{{.TotalAdded}}</td></tr>
+<tr><td class="removed">Removed</td><td>{{.TotalRemoved}}</td></tr>
+<tr><td class="modified">Modified</td><td>{{.TotalChanged}}</td></tr>
+<tr><td>Size change</td><td>{{delta .SizeDelta}}</td></tr>
+</table>
+{{with .Violations}}<p class="removed"><strong>Policy violated:</strong></p>
+<ul>
+{{range .}}<li class="removed">{{.}}</li>
+{{end}}</ul>
+{{end}}{{with .TopSizeChanges 10}}<details open>
+<summary>Top size changes</summary>
+<table>
+<tr><th>Change</th><th>Path</th><th>Status</th></tr>
+{{range .}}<tr><td>{{delta .Delta}}</td><td><code>{{.Path}}</code></td><td class="{{.Status}}">{{.Status}}</td></tr>
+{{end}}</table>
+</details>
+{{end}}{{with .AddedExecutables}}<details open>
+<summary>Added executables ({{len .}})</summary>
+<ul>
+{{range .}}<li class="added"><code>{{.Path}}</code> ({{.NewMode}}, {{size .NewSize}})</li>
+{{end}}</ul>
+</details>
+{{end}}{{with .RemovedExecutables}}<details open>
+<summary>Removed executables ({{len .}})</summary>
+<ul>
+{{range .}}<li class="removed"><code>{{.Path}}</code> ({{.OldMode}}, {{size .OldSize}})</li>
+{{end}}</ul>
+</details>
+{{end}}{{with .Added}}<details>
+<summary>Added files ({{len .}})</summary>
+<ul>
+{{range .}}<li class="added"><code>{{.Path}}</code> ({{.Type}}, {{size .NewSize}})</li>
+{{end}}</ul>
+</details>
+{{end}}{{with .Removed}}<details>
+<summary>Removed files ({{len .}})</summary>
+<ul>
+{{range .}}<li class="removed"><code>{{.Path}}</code> ({{.Type}}, {{size .OldSize}})</li>
+{{end}}</ul>
+</details>
+{{end}}{{with .Modified}}<details>
+<summary>Modified files ({{len .}})</summary>
+<ul>
+{{range .}}<li class="modified"><code>{{.Path}}</code> ({{details .}})</li>
+{{end}}</ul>
+</details>
+{{end}}</body>
+</html>
+`))
+
+// ToHTML returns a standalone HTML report
+func (r *DiffResult) ToHTML() (string, error) {
+	var buf bytes.Buffer
+	if err := htmlReport.Execute(&buf, r); err != nil {
+		return "", fmt.Errorf("render diff result to HTML: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite groups test cases of a single diff status
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is a single changed file or policy violation
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// junitFailure marks a failed test case
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// ToJUnit returns a JUnit XML report with a test case per changed file
+func (r *DiffResult) ToJUnit() (string, error) {
+	report := junitTestSuites{Name: fmt.Sprintf("artship diff %s %s", r.SourceImage, r.TargetImage)}
+
+	groups := []struct {
+		status  FileStatus
+		entries []DiffEntry
+	}{
+		{FileStatusAdded, r.Added},
+		{FileStatusRemoved, r.Removed},
+		{FileStatusModified, r.Modified},
+	}
+
+	for _, group := range groups {
+		suite := junitTestSuite{Name: string(group.status)}
+
+		for _, entry := range group.entries {
+			var details string
+			switch group.status {
+			case FileStatusAdded:
+				details = fmt.Sprintf("%s, %s", entry.Type, tools.FormatSize(entry.NewSize))
+			case FileStatusRemoved:
+				details = fmt.Sprintf("%s, %s", entry.Type, tools.FormatSize(entry.OldSize))
+			default:
+				details = strings.Trim(entry.changeDetails(), "()")
+			}
+
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      entry.Path,
+				ClassName: string(group.status),
+				SystemOut: details,
+			})
+		}
+
+		suite.Tests = len(suite.Cases)
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+	}
+
+	if len(r.Violations) > 0 {
+		suite := junitTestSuite{Name: "policy", Tests: len(r.Violations), Failures: len(r.Violations)}
+		for _, violation := range r.Violations {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      violation,
+				ClassName: "policy",
+				Failure:   &junitFailure{Message: violation},
+			})
+		}
+
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Suites = append(report.Suites, suite)
+	}
+
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal diff result to JUnit XML: %w", err)
+	}
+
+	return xml.Header + string(data), nil
+}
+
+// formatDelta formats a size difference with a sign
+func formatDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + tools.FormatSize(-delta)
+	}
+
+	return "+" + tools.FormatSize(delta)
+}
+
+// abs returns the absolute value
+func abs(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownCode(t *testing.T) {
+	tests := []struct {
+		s     string
+		table bool
+		want  string
+	}{
+		{s: "usr/lib/libfoo.so", want: "`usr/lib/libfoo.so`"},
+		// Markdown emphasis and escapes are not interpreted inside code spans
+		{s: "lib/__pycache__/*.pyc", want: "`lib/__pycache__/*.pyc`"},
+		{s: `C:\path`, want: "`C:\\path`"},
+		{s: "a|b", want: "`a|b`"},
+		{s: "a|b", table: true, want: "`a\\|b`"},
+		{s: "it`s", want: "``it`s``"},
+		{s: "a``b`", want: "``` a``b` ```"},
+		{s: "`start", want: "`` `start ``"},
+	}
+
+	for _, tt := range tests {
+		if got := markdownCode(tt.s, tt.table); got != tt.want {
+			t.Errorf("markdownCode(%q, %v) = %q, want %q", tt.s, tt.table, got, tt.want)
+		}
+	}
+}
+
+func TestToMarkdownPaths(t *testing.T) {
+	result := &DiffResult{
+		SourceImage: "app:v1",
+		TargetImage: "app:v2",
+		Added:       []DiffEntry{{Path: "app/__init__.py", Type: "file", NewSize: 10}, {Path: "etc/a|b.conf", Type: "file", NewSize: 20}},
+		TotalAdded:  2,
+	}
+
+	md := result.ToMarkdown()
+	for _, want := range []string{
+		"### Image diff: `app:v1` → `app:v2`",
+		"| +20 B | `etc/a\\|b.conf` | added |",
+		"- `app/__init__.py` (file, 10 B)",
+		"- `etc/a|b.conf` (file, 20 B)",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("the report does not contain %q:\n%s", want, md)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+// dirPrefix marks a local directory used instead of an image reference
+const dirPrefix = "dir:"
+
+// files returns the files of an image or of a local directory ('dir:/path')
+func (c *Client) files(ctx context.Context, ref string) (*fileSet, error) {
+	if root, ok := strings.CutPrefix(ref, dirPrefix); ok {
+		return dirFiles(root)
+	}
+
+	return c.imageFiles(ctx, ref)
+}
+
+// dirFiles walks a local directory without following symlinks
+func dirFiles(root string) (*fileSet, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("stat directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return nil, fmt.Errorf("'%s' is not a directory", root)
+	}
+
+	set := &fileSet{
+		root:   root,
+		files:  make(map[string]*FileInfo),
+		origin: make(map[string]int),
+	}
+
+	err = filepath.WalkDir(root, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, filePath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		file := &FileInfo{
+			Path: rel,
+			Mode: fmt.Sprintf("%04o", tarMode(info.Mode())),
+		}
+
+		mode := info.Mode()
+		switch {
+		case mode.IsRegular():
+			file.Type = "file"
+			file.Size = info.Size()
+		case mode&fs.ModeSymlink != 0:
+			file.Type = "symlink"
+			if file.Link, err = os.Readlink(filePath); err != nil {
+				return err
+			}
+		case mode&fs.ModeNamedPipe != 0:
+			file.Type = "fifo"
+		case mode&fs.ModeCharDevice != 0:
+			file.Type = "chardev"
+		case mode&fs.ModeDevice != 0:
+			file.Type = "blockdev"
+		default:
+			// Sockets and other special files can't be stored in images
+			return nil
+		}
+
+		set.files[rel] = file
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk directory '%s': %w", root, err)
+	}
+
+	return set, nil
+}
+
+// readDirFiles opens the requested files of a local directory and calls f for each of them
+func readDirFiles(root string, paths map[int]map[string]bool, f func(p string, r io.Reader) error) error {
+	for _, group := range paths {
+		for p := range group {
+			file, err := os.Open(filepath.Join(root, filepath.FromSlash(p)))
+			if err != nil {
+				return fmt.Errorf("open '%s': %w", p, err)
+			}
+
+			err = f(p, file)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveHardlinks replaces hardlinks with the info of their targets
+func (s *fileSet) resolveHardlinks() {
+	for p, file := range s.files {
+		if file.Type != "hardlink" {
+			continue
+		}
+
+		target, ok := s.files[tools.CleanPath(file.Link)]
+		if !ok || target.Type != "file" {
+			continue
+		}
+
+		resolved := *target
+		resolved.Path = p
+		s.files[p] = &resolved
+		s.origin[p] = s.origin[target.Path]
+
+		if s.links == nil {
+			s.links = make(map[string]string)
+		}
+		s.links[p] = target.Path
+	}
+}
+
+// tarMode converts file mode to tar permission bits including setuid, setgid and sticky bits
+func tarMode(mode fs.FileMode) int64 {
+	res := int64(mode.Perm())
+	if mode&fs.ModeSetuid != 0 {
+		res |= 0o4000
+	}
+	if mode&fs.ModeSetgid != 0 {
+		res |= 0o2000
+	}
+	if mode&fs.ModeSticky != 0 {
+		res |= 0o1000
+	}
+
+	return res
+}
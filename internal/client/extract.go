@@ -17,7 +17,7 @@ type progressTracker interface {
 	Written() int64
 }
 
-// spinner provides a simple loading animation
+// spinner provides a simple loading animation on stderr, so it never mixes with streamed output
 type spinner struct {
 	frames  []string
 	message string
@@ -46,7 +46,7 @@ func (s *spinner) start() {
 		for {
 			select {
 			case <-s.stop:
-				fmt.Print("\r\033[K") // Clear the line
+				fmt.Fprint(os.Stderr, "\r\033[K") // Clear the line
 				return
 			default:
 				msg := s.message
@@ -56,7 +56,7 @@ func (s *spinner) start() {
 						msg = fmt.Sprintf("%s (%s)", s.message, logs.Gray(tools.FormatSize(written)))
 					}
 				}
-				fmt.Printf("\r%s %s", s.frames[i%len(s.frames)], msg)
+				fmt.Fprintf(os.Stderr, "\r%s %s", s.frames[i%len(s.frames)], msg)
 				i++
 				time.Sleep(80 * time.Millisecond)
 			}
@@ -67,7 +67,7 @@ func (s *spinner) start() {
 func (s *spinner) stopSpinner() {
 	close(s.stop)
 	s.wg.Wait()
-	fmt.Print("\r\033[K") // Clear the line
+	fmt.Fprint(os.Stderr, "\r\033[K") // Clear the line
 }
 
 // bytesWriter wraps an io.Writer and tracks bytes written
@@ -127,8 +127,8 @@ func (br *bytesReader) Written() int64 {
 	return br.read
 }
 
-// Extract extracts all files from an OCI image
-func (c *Client) Extract(ctx context.Context, imageRef, output string) error {
+// Extract extracts all files from an OCI image, or from a single layer by digest or index
+func (c *Client) Extract(ctx context.Context, imageRef, layer, output string, opts *tools.ExtractOptions) error {
 	startTime := time.Now()
 
 	if imageRef == "" {
@@ -139,7 +139,7 @@ func (c *Client) Extract(ctx context.Context, imageRef, output string) error {
 		return fmt.Errorf("no output provided")
 	}
 
-	img, err := c.extractImage(ctx, imageRef)
+	img, err := c.extract(ctx, imageRef, layer)
 	if err != nil {
 		return err
 	}
@@ -154,6 +154,10 @@ func (c *Client) Extract(ctx context.Context, imageRef, output string) error {
 	spin.setTracker(br)
 	spin.start()
 
+	// The output is tracked, so a failed extraction does not leave it behind
+	var created tools.CreatedPaths
+	created.Track(output)
+
 	c.logger.Debug("Creating output directory: %s", output)
 	if err = os.MkdirAll(output, 0755); err != nil {
 		spin.stopSpinner()
@@ -161,25 +165,66 @@ func (c *Client) Extract(ctx context.Context, imageRef, output string) error {
 	}
 
 	c.logger.Debug("Extracting...")
-	res, err := tools.CopyTar(ctx, br, output)
+	res, err := tools.CopyTar(ctx, br, output, opts)
 	if err != nil {
 		spin.stopSpinner()
+		if cleanupErr := created.Remove(); cleanupErr != nil {
+			c.logger.Warn("Could not remove the output directory: %v", cleanupErr)
+		}
+
 		return fmt.Errorf("copy the image '%s' to the target path '%s': %w", imageRef, output, err)
 	}
 
+	// Hardlinks to files left out by the filters need a second pass over the image
+	if len(res.PendingLinks) > 0 {
+		c.logger.Debug("Copying %d hardlink targets left out by the filters...", len(res.PendingLinks))
+		if err = c.extractLinkTargets(ctx, imageRef, layer, res.PendingLinks); err != nil {
+			spin.stopSpinner()
+			if cleanupErr := created.Remove(); cleanupErr != nil {
+				c.logger.Warn("Could not remove the output directory: %v", cleanupErr)
+			}
+
+			return fmt.Errorf("copy hardlink targets of the image '%s': %w", imageRef, err)
+		}
+	}
+
 	spin.stopSpinner()
 
+	for _, warning := range res.Warnings {
+		c.logger.Warn("%s", warning)
+	}
+
 	executionTime := time.Since(startTime)
 	c.logger.Info(logs.BoldGreen("✓")+" Successfully extracted image: %s", logs.Blue(output))
 	c.logger.Info(logs.Green("  📁 Files extracted: ")+"%d", res.FilesExtracted)
 	c.logger.Info(logs.Green("  📂 Directories created: ")+"%d", res.DirsCreated)
 	c.logger.Info(logs.Green("  🔗 Links created: ")+"%d", res.LinksCreated)
+	if res.EntriesSkipped > 0 {
+		c.logger.Info(logs.Green("  🚫 Entries skipped: ")+"%d", res.EntriesSkipped)
+	}
+	if res.DevicesCreated > 0 || res.DevicesRecorded > 0 {
+		c.logger.Info(logs.Green("  🔌 Special files created: ")+"%d", res.DevicesCreated)
+	}
+	if res.DevicesRecorded > 0 && opts != nil && opts.DevicesManifest != "" {
+		c.logger.Info(logs.Green("  📝 Special files recorded: ")+"%d in %s", res.DevicesRecorded, logs.Blue(opts.DevicesManifest))
+	}
 	c.logger.Info(logs.Green("  💾 Total size: ")+"%s", tools.FormatSize(res.TotalSize))
 	c.logger.Info(logs.Green("  ⏱  Time: ")+"%s", executionTime.Round(time.Millisecond).String())
 
 	return nil
 }
 
+// extractLinkTargets copies the content of hardlink targets from a new stream of the image
+func (c *Client) extractLinkTargets(ctx context.Context, imageRef, layer string, links map[string][]string) error {
+	img, err := c.extract(ctx, imageRef, layer)
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+
+	return tools.CopyLinkTargets(img, links)
+}
+
 // ExtractTar extracts raw tar archive from an OCI image
 func (c *Client) ExtractTar(ctx context.Context, imageRef string, output string) error {
 	startTime := time.Now()
@@ -198,12 +243,14 @@ func (c *Client) ExtractTar(ctx context.Context, imageRef string, output string)
 	}
 	defer img.Close()
 
-	// Create or open the output file
-	out, err := os.Create(output)
-	if err != nil {
-		return fmt.Errorf("create output file: %w", err)
+	// Create or open the output file, '-' streams the archive to stdout
+	out := os.Stdout
+	if output != "-" {
+		if out, err = os.Create(output); err != nil {
+			return fmt.Errorf("create output file: %w", err)
+		}
+		defer out.Close()
 	}
-	defer out.Close()
 
 	// Create bytes writer to track progress
 	bw := newBytesWriter(out)
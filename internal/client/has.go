@@ -12,7 +12,9 @@ import (
 
 var ErrNotFound = errors.New("artifact not found")
 
-func (c *Client) Has(ctx context.Context, imageRef, artifact string) error {
+// Has checks if any path in the image matches the artifact name, glob or, with regex, regular expression.
+// A layer digest or index searches that layer only
+func (c *Client) Has(ctx context.Context, imageRef, layer, artifact string, regex bool) error {
 	if imageRef == "" {
 		return fmt.Errorf("no image ref provided")
 	}
@@ -21,7 +23,12 @@ func (c *Client) Has(ctx context.Context, imageRef, artifact string) error {
 		return fmt.Errorf("no artifact provided")
 	}
 
-	img, err := c.extractImage(ctx, imageRef)
+	matcher, err := tools.NewMatcher(artifact, regex)
+	if err != nil {
+		return err
+	}
+
+	img, err := c.extract(ctx, imageRef, layer)
 	if err != nil {
 		return err
 	}
@@ -30,7 +37,7 @@ func (c *Client) Has(ctx context.Context, imageRef, artifact string) error {
 	var found bool
 	c.logger.Debug("Searching for artifact...")
 	err = tools.WalkTar(img, func(r io.Reader, header *tar.Header) error {
-		if tools.MatchName(header.Name, artifact) {
+		if matcher.Match(header.Name) {
 			c.logger.Debug("Found matching artifact: %s", header.Name)
 			found = true
 			return tools.ErrStopWalk
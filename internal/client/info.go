@@ -20,7 +20,7 @@ type Artifact struct {
 // String returns a formatted string representation of the artifact
 func (a Artifact) String(header bool) string {
 	sizeStr := tools.FormatSize(a.Size)
-	if a.Type == "dir" || a.Type == "symlink" || a.Type == "hardlink" {
+	if a.Type == "dir" || a.Type == "symlink" || a.Type == "hardlink" || a.Type == ArtifactTypeDeleted || a.Type == ArtifactTypeOpaque {
 		sizeStr = "-"
 	}
 
@@ -35,8 +35,10 @@ func (a Artifact) String(header bool) string {
 	return result
 }
 
-// GetArtifact retrieves detailed information about a specific artifact
-func (c *Client) GetArtifact(ctx context.Context, imageRef, artifact string) (*Artifact, error) {
+// GetArtifacts retrieves detailed information about artifacts matching the name, glob or,
+// with regex, regular expression. A plain name returns only the first match.
+// A layer digest or index searches that layer only
+func (c *Client) GetArtifacts(ctx context.Context, imageRef, layer, artifact string, regex bool) (ArtifactList, error) {
 	if imageRef == "" {
 		return nil, fmt.Errorf("no image ref provided")
 	}
@@ -45,23 +47,32 @@ func (c *Client) GetArtifact(ctx context.Context, imageRef, artifact string) (*A
 		return nil, fmt.Errorf("no artifact provided")
 	}
 
-	img, err := c.extractImage(ctx, imageRef)
+	matcher, err := tools.NewMatcher(artifact, regex)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := c.extract(ctx, imageRef, layer)
 	if err != nil {
 		return nil, err
 	}
 	defer img.Close()
 
-	var info *Artifact
+	var infos ArtifactList
 	c.logger.Debug("Searching for artifact...")
 	err = tools.WalkTar(img, func(_ io.Reader, header *tar.Header) error {
-		if tools.MatchName(header.Name, artifact) {
-			info = &Artifact{
-				Path: header.Name,
-				Size: header.Size,
-				Type: tools.GetArtifactType(header.Typeflag),
-				Mode: fmt.Sprintf("%04o", header.Mode),
-			}
+		if !matcher.Match(header.Name) {
+			return nil
+		}
+
+		infos = append(infos, Artifact{
+			Path: header.Name,
+			Size: header.Size,
+			Type: tools.GetArtifactType(header.Typeflag),
+			Mode: fmt.Sprintf("%04o", header.Mode),
+		})
 
+		if !matcher.IsPattern() {
 			return tools.ErrStopWalk
 		}
 
@@ -71,10 +82,10 @@ func (c *Client) GetArtifact(ctx context.Context, imageRef, artifact string) (*A
 		return nil, fmt.Errorf("walk the image: %w", err)
 	}
 
-	if info == nil {
+	if len(infos) == 0 {
 		return nil, fmt.Errorf("artifact '%s' not found", artifact)
 	}
 
 	c.logger.Debug("Successfully retrieved artifact info")
-	return info, nil
+	return infos, nil
 }
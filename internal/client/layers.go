@@ -0,0 +1,344 @@
+package client
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	crv1 "github.com/google/go-containerregistry/pkg/v1"
+
+	"github.com/ipaqsa/artship/internal/logs"
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+// LayerFile is a file changed by a layer
+type LayerFile struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	Size int64  `json:"size"`
+}
+
+// LayerHistory describes what a single layer did relative to the layers below it
+type LayerHistory struct {
+	Index     int    `json:"index"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"` // Compressed layer size
+	CreatedBy string `json:"createdBy,omitempty"`
+	Created   string `json:"created,omitempty"`
+	Comment   string `json:"comment,omitempty"`
+
+	// Added contains files not present in lower layers
+	Added []LayerFile `json:"added"`
+	// Modified contains files replacing files from lower layers
+	Modified []LayerFile `json:"modified"`
+	// Deleted contains lower layer files removed by whiteouts and opaque directories
+	Deleted []LayerFile `json:"deleted"`
+	// Opaque contains directories whose lower layer contents are hidden
+	Opaque []string `json:"opaque,omitempty"`
+}
+
+// LayerHistoryList contains the change history of image layers
+type LayerHistoryList struct {
+	Image  string         `json:"image"`
+	Layers []LayerHistory `json:"layers"`
+	// Detailed is set when a single layer is requested and file lists are printed
+	Detailed bool `json:"-"`
+}
+
+// String returns formatted layer history with colors
+func (l *LayerHistoryList) String() string {
+	if l.Detailed {
+		var sb strings.Builder
+		for _, layer := range l.Layers {
+			sb.WriteString(layer.String())
+		}
+		return sb.String()
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("  %-5s %-19s %10s %8s %8s %8s  %s\n",
+		"#", "DIGEST", "SIZE", "ADDED", "MODIFIED", "DELETED", "CREATED BY"))
+
+	for _, layer := range l.Layers {
+		sb.WriteString(fmt.Sprintf("  %-5d %-19s %10s %s %s %s  %s\n",
+			layer.Index, shortDigest(layer.Digest), tools.FormatSize(layer.Size),
+			logs.Green(fmt.Sprintf("%8s", "+"+strconv.Itoa(len(layer.Added)))),
+			logs.Yellow(fmt.Sprintf("%8s", "~"+strconv.Itoa(len(layer.Modified)))),
+			logs.Red(fmt.Sprintf("%8s", "-"+strconv.Itoa(len(layer.Deleted)))),
+			truncate(layer.CreatedBy, 80)))
+	}
+
+	return sb.String()
+}
+
+// String returns the layer details with the changed files
+func (l *LayerHistory) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(logs.BoldBlue(fmt.Sprintf("Layer %d", l.Index)) + "\n")
+	sb.WriteString(fmt.Sprintf("  Digest:     %s\n", l.Digest))
+	sb.WriteString(fmt.Sprintf("  Size:       %s\n", tools.FormatSize(l.Size)))
+	if l.Created != "" {
+		sb.WriteString(fmt.Sprintf("  Created:    %s\n", l.Created))
+	}
+	if l.CreatedBy != "" {
+		sb.WriteString(fmt.Sprintf("  Created by: %s\n", l.CreatedBy))
+	}
+	if l.Comment != "" {
+		sb.WriteString(fmt.Sprintf("  Comment:    %s\n", l.Comment))
+	}
+
+	sb.WriteString(fmt.Sprintf("  Changes:    %s, %s, %s\n",
+		logs.Green(fmt.Sprintf("%d added", len(l.Added))),
+		logs.Yellow(fmt.Sprintf("%d modified", len(l.Modified))),
+		logs.Red(fmt.Sprintf("%d deleted", len(l.Deleted)))))
+
+	type change struct {
+		status string
+		file   LayerFile
+	}
+
+	var changes []change
+	for _, file := range l.Added {
+		changes = append(changes, change{"added", file})
+	}
+	for _, file := range l.Modified {
+		changes = append(changes, change{"modified", file})
+	}
+	for _, file := range l.Deleted {
+		changes = append(changes, change{"removed", file})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].file.Path < changes[j].file.Path })
+
+	if len(changes) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, c := range changes {
+		details := c.file.Type
+		if c.file.Type == "file" {
+			details = tools.FormatSize(c.file.Size)
+		}
+		sb.WriteString("  " + logs.FormatDiffLine(c.status, "/"+c.file.Path, "("+details+")") + "\n")
+	}
+
+	for _, dir := range l.Opaque {
+		sb.WriteString("  " + logs.Gray(fmt.Sprintf("opaque directory: /%s", dir)) + "\n")
+	}
+
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// ToJSON returns JSON representation of the layer history
+func (l *LayerHistoryList) ToJSON() (string, error) {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal layer history to JSON: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// Layers returns what each image layer added, modified and deleted.
+// If layerSpec is set (digest or index), only that layer is returned
+func (c *Client) Layers(ctx context.Context, imageRef, layerSpec string) (*LayerHistoryList, error) {
+	if imageRef == "" {
+		return nil, fmt.Errorf("no image ref provided")
+	}
+
+	img, err := c.image(ctx, imageRef)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := img.ConfigFile()
+	if err != nil {
+		return nil, fmt.Errorf("get image config: %w", err)
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("get image layers: %w", err)
+	}
+
+	last := len(layers) - 1
+	selected := -1
+	if layerSpec != "" {
+		if selected, err = selectLayer(layers, layerSpec); err != nil {
+			return nil, err
+		}
+		last = selected
+	}
+
+	history := layerHistory(config, len(layers))
+
+	result := &LayerHistoryList{
+		Image:    imageRef,
+		Layers:   []LayerHistory{},
+		Detailed: selected >= 0,
+	}
+
+	// Merged filesystem of the layers below the current one
+	merged := make(map[string]*tar.Header)
+	for i, layer := range layers[:last+1] {
+		digest, err := layer.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("get layer digest: %w", err)
+		}
+
+		size, err := layer.Size()
+		if err != nil {
+			return nil, fmt.Errorf("get layer size: %w", err)
+		}
+
+		c.logger.Debug("Reading layer %d: %s", i, digest)
+
+		rc, err := layer.Uncompressed()
+		if err != nil {
+			return nil, fmt.Errorf("read the layer '%s': %w", digest, err)
+		}
+
+		changes, err := tools.ReadLayer(rc, nil)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read the layer '%s': %w", digest, err)
+		}
+
+		entry := LayerHistory{
+			Index:    i,
+			Digest:   digest.String(),
+			Size:     size,
+			Added:    []LayerFile{},
+			Modified: []LayerFile{},
+			Deleted:  []LayerFile{},
+			Opaque:   changes.Opaque,
+		}
+
+		if h := history[i]; h != nil {
+			entry.CreatedBy = h.CreatedBy
+			entry.Comment = h.Comment
+			if !h.Created.IsZero() {
+				entry.Created = h.Created.Format("2006-01-02 15:04:05 MST")
+			}
+		}
+
+		// Whiteouts apply to the lower layers only
+		tools.RemoveHidden(merged, changes, func(p string, header *tar.Header) {
+			entry.Deleted = append(entry.Deleted, newLayerFile(p, header))
+		})
+
+		for p, header := range changes.Entries {
+			lower, ok := merged[p]
+			merged[p] = header
+
+			switch {
+			case !ok:
+				entry.Added = append(entry.Added, newLayerFile(p, header))
+			case header.Typeflag == tar.TypeDir && lower.Typeflag == tar.TypeDir && !metadataChanged(lower, header):
+				// Parent directories are repeated in layers, only metadata changes are reported
+			default:
+				entry.Modified = append(entry.Modified, newLayerFile(p, header))
+			}
+		}
+
+		if selected >= 0 && i != selected {
+			continue
+		}
+
+		sortLayerFiles(entry.Added)
+		sortLayerFiles(entry.Modified)
+		sortLayerFiles(entry.Deleted)
+
+		result.Layers = append(result.Layers, entry)
+	}
+
+	return result, nil
+}
+
+// selectLayer finds the layer by digest or index, negative indexes count from the top layer
+func selectLayer(layers []crv1.Layer, spec string) (int, error) {
+	if index, err := strconv.Atoi(spec); err == nil {
+		if index < 0 {
+			index += len(layers)
+		}
+
+		if index < 0 || index >= len(layers) {
+			return 0, fmt.Errorf("layer index %s is out of range (image has %d layers)", spec, len(layers))
+		}
+
+		return index, nil
+	}
+
+	digest, err := crv1.NewHash(spec)
+	if err != nil {
+		return 0, fmt.Errorf("parse layer digest: %w", err)
+	}
+
+	for i, layer := range layers {
+		layerHash, err := layer.Digest()
+		if err != nil {
+			return 0, fmt.Errorf("get layer digest: %w", err)
+		}
+
+		if layerHash == digest {
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("layer '%s' not found", spec)
+}
+
+// layerHistory maps the config history entries to layers, skipping empty layer entries
+func layerHistory(config *crv1.ConfigFile, count int) []*crv1.History {
+	res := make([]*crv1.History, count)
+
+	i := 0
+	for idx := range config.History {
+		if config.History[idx].EmptyLayer {
+			continue
+		}
+
+		if i == count {
+			break
+		}
+
+		res[i] = &config.History[idx]
+		i++
+	}
+
+	return res
+}
+
+// metadataChanged checks if the entry permissions or ownership differ
+func metadataChanged(a, b *tar.Header) bool {
+	return a.Mode != b.Mode || a.Uid != b.Uid || a.Gid != b.Gid
+}
+
+// newLayerFile creates a layer file from the tar header
+func newLayerFile(p string, header *tar.Header) LayerFile {
+	return LayerFile{
+		Path: p,
+		Type: tools.GetArtifactType(header.Typeflag),
+		Size: header.Size,
+	}
+}
+
+// sortLayerFiles sorts layer files by path
+func sortLayerFiles(files []LayerFile) {
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+}
+
+// truncate shortens the string to the maximum length
+func truncate(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= max {
+		return s
+	}
+
+	return s[:max-3] + "..."
+}
@@ -9,6 +9,14 @@ import (
 	"github.com/ipaqsa/artship/internal/tools"
 )
 
+// Pseudo artifact types reporting whiteouts of a single layer
+const (
+	// ArtifactTypeDeleted is a lower layer path removed by the layer
+	ArtifactTypeDeleted = "deleted"
+	// ArtifactTypeOpaque is a directory whose lower layer contents are hidden by the layer
+	ArtifactTypeOpaque = "opaque"
+)
+
 // ArtifactList represents a collection of artifacts
 type ArtifactList []Artifact
 
@@ -36,12 +44,21 @@ func (l ArtifactList) String(detailed bool) string {
 	return res
 }
 
-// List lists all available artifacts in image
-func (c *Client) List(ctx context.Context, imageRef, filter, layerDigest string) (ArtifactList, error) {
+// List lists all available artifacts in image.
+// If a pattern is provided, only paths matching the name, glob or, with regex, regular expression are listed
+func (c *Client) List(ctx context.Context, imageRef, filter, layerDigest, pattern string, regex bool) (ArtifactList, error) {
 	if imageRef == "" {
 		return nil, fmt.Errorf("no image ref provided")
 	}
 
+	var matcher *tools.Matcher
+	if pattern != "" {
+		var err error
+		if matcher, err = tools.NewMatcher(pattern, regex); err != nil {
+			return nil, err
+		}
+	}
+
 	c.logger.Debug("Walking the image...")
 
 	reader, err := c.extract(ctx, imageRef, layerDigest)
@@ -51,26 +68,46 @@ func (c *Client) List(ctx context.Context, imageRef, filter, layerDigest string)
 	defer reader.Close()
 
 	var artifacts []Artifact
-	c.logger.Debug("Scanning image artifacts...")
-	err = tools.WalkTar(reader, func(_ io.Reader, header *tar.Header) error {
-		artType := tools.GetArtifactType(header.Typeflag)
+	add := func(artifact Artifact) {
+		if matcher != nil && !matcher.Match(artifact.Path) {
+			return
+		}
 
 		// Apply type filter
-		if filter != "" && filter != "all" && filter != artType {
-			return nil
+		if filter == "" || filter == "all" || filter == artifact.Type {
+			artifacts = append(artifacts, artifact)
 		}
+	}
 
-		artifacts = append(artifacts, Artifact{
+	walk := func(_ io.Reader, header *tar.Header) error {
+		add(Artifact{
 			Path: header.Name,
 			Size: header.Size,
-			Type: artType,
+			Type: tools.GetArtifactType(header.Typeflag),
 			Mode: fmt.Sprintf("%04o", header.Mode),
 		})
 
 		return nil
-	})
-	if err != nil {
-		return nil, fmt.Errorf("walk the image: %w", err)
+	}
+
+	c.logger.Debug("Scanning image artifacts...")
+	if layerDigest == "" {
+		if err = tools.WalkTar(reader, walk); err != nil {
+			return nil, fmt.Errorf("walk the image: %w", err)
+		}
+	} else {
+		// A single layer may remove files of the layers below, report them explicitly
+		changes, err := tools.ReadLayer(reader, walk)
+		if err != nil {
+			return nil, fmt.Errorf("walk the layer: %w", err)
+		}
+
+		for _, p := range changes.Deleted {
+			add(Artifact{Path: p, Type: ArtifactTypeDeleted, Mode: "-"})
+		}
+		for _, dir := range changes.Opaque {
+			add(Artifact{Path: dir + "/", Type: ArtifactTypeOpaque, Mode: "-"})
+		}
 	}
 
 	c.logger.Debug("Found %d artifacts", len(artifacts))
@@ -3,6 +3,7 @@ package client
 import (
 	"context"
 	"fmt"
+	"sort"
 
 	"github.com/ipaqsa/artship/internal/tools"
 
@@ -18,12 +19,14 @@ type ImageMeta struct {
 	OS           string            `json:"os" yaml:"OS"`
 	ManifestSize string            `json:"manifestSize" yaml:"ManifestSize"`
 	Size         string            `json:"size" yaml:"Size"`
+	SizeBytes    int64             `json:"sizeBytes" yaml:"-"`
 	Created      string            `json:"created,omitempty" yaml:"Created,omitempty"`
 	Env          []string          `json:"env,omitempty" yaml:"Env,omitempty"`
 	Cmd          []string          `json:"cmd,omitempty" yaml:"Cmd,omitempty"`
 	Entrypoint   []string          `json:"entrypoint,omitempty" yaml:"Entrypoint,omitempty"`
 	WorkingDir   string            `json:"workingDir,omitempty" yaml:"WorkingDir,omitempty"`
 	User         string            `json:"user,omitempty" yaml:"User,omitempty"`
+	ExposedPorts []string          `json:"exposedPorts,omitempty" yaml:"ExposedPorts,omitempty"`
 	Labels       map[string]string `json:"labels,omitempty" yaml:"Labels,omitempty"`
 	Annotations  map[string]string `json:"annotations,omitempty" yaml:"Annotations,omitempty"`
 	Layers       []LayerMeta       `json:"layers" yaml:"Layers"`
@@ -120,6 +123,7 @@ func (c *Client) GetImageMeta(ctx context.Context, imageRef string) (*ImageMeta,
 		Architecture: config.Architecture,
 		OS:           config.OS,
 		Size:         tools.FormatSize(size),
+		SizeBytes:    size,
 		ManifestSize: tools.FormatSize(manifestSize),
 		Layers:       layerMetas,
 	}
@@ -148,6 +152,11 @@ func (c *Client) GetImageMeta(ctx context.Context, imageRef string) (*ImageMeta,
 		meta.User = config.Config.User
 	}
 
+	for port := range config.Config.ExposedPorts {
+		meta.ExposedPorts = append(meta.ExposedPorts, port)
+	}
+	sort.Strings(meta.ExposedPorts)
+
 	if len(config.Config.Labels) > 0 {
 		meta.Labels = config.Config.Labels
 	}
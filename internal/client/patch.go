@@ -0,0 +1,131 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+const (
+	// maxPatchFileSize is the size of the largest file compared line by line in a patch
+	maxPatchFileSize = 10 * 1024 * 1024
+	// patchContext is the number of context lines around changes
+	patchContext = 3
+)
+
+// buildPatch creates a git-compatible patch for the modified regular files matching the globs
+func (c *Client) buildPatch(source, target *fileSet, modified []DiffEntry, globs []string) (string, error) {
+	patterns, err := compileGlobs(globs)
+	if err != nil {
+		return "", err
+	}
+
+	var entries []DiffEntry
+	sourcePaths := make(map[int]map[string]bool)
+	targetPaths := make(map[int]map[string]bool)
+	for _, entry := range modified {
+		if entry.Type != "file" || slices.Contains(entry.Reasons, ReasonType) {
+			continue
+		}
+
+		if len(patterns) > 0 && !matchAnyRegexp(patterns, entry.Path) {
+			continue
+		}
+
+		entries = append(entries, entry)
+
+		if slices.Contains(entry.Reasons, ReasonSize) || slices.Contains(entry.Reasons, ReasonContent) {
+			source.addPath(sourcePaths, entry.Path)
+			target.addPath(targetPaths, entry.Path)
+		}
+	}
+
+	c.logger.Debug("Building patch for %d files", len(entries))
+
+	oldContents, err := c.readContents(source, sourcePaths)
+	if err != nil {
+		return "", err
+	}
+
+	newContents, err := c.readContents(target, targetPaths)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, entry := range entries {
+		oldMode, newMode := gitMode(entry.OldMode), gitMode(entry.NewMode)
+		// Contents are read only for files whose content differs
+		oldContent, contentChanged := oldContents[source.realPath(entry.Path)]
+		newContent := newContents[target.realPath(entry.Path)]
+
+		if oldMode == newMode && !contentChanged {
+			// Only owner or permission bits git does not track differ
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", entry.Path, entry.Path))
+		if oldMode != newMode {
+			sb.WriteString(fmt.Sprintf("old mode %s\nnew mode %s\n", oldMode, newMode))
+		}
+
+		if !contentChanged {
+			continue
+		}
+
+		if oldContent == nil || newContent == nil || tools.IsBinary(oldContent) || tools.IsBinary(newContent) {
+			sb.WriteString(fmt.Sprintf("Binary files a/%s and b/%s differ\n", entry.Path, entry.Path))
+			continue
+		}
+
+		hunks := tools.UnifiedDiff(string(oldContent), string(newContent), patchContext)
+		if hunks == "" {
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("--- a/%s\n+++ b/%s\n", entry.Path, entry.Path))
+		sb.WriteString(hunks)
+	}
+
+	return sb.String(), nil
+}
+
+// readContents reads the requested files into memory.
+// Files larger than the patch limit are present in the result with nil content
+func (c *Client) readContents(set *fileSet, paths map[int]map[string]bool) (map[string][]byte, error) {
+	contents := make(map[string][]byte)
+
+	err := c.readFiles(set, paths, func(p string, r io.Reader) error {
+		if set.files[p].Size > maxPatchFileSize {
+			contents[p] = nil
+			return nil
+		}
+
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("read '%s': %w", p, err)
+		}
+		contents[p] = content
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return contents, nil
+}
+
+// gitMode converts file permissions to the file modes git tracks
+func gitMode(mode string) string {
+	perm, err := strconv.ParseInt(mode, 8, 64)
+	if err == nil && perm&0o111 != 0 {
+		return "100755"
+	}
+
+	return "100644"
+}
@@ -0,0 +1,589 @@
+package client
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ipaqsa/artship/internal/logs"
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+const (
+	dpkgStatusPath    = "var/lib/dpkg/status"
+	dpkgStatusDirPath = "var/lib/dpkg/status.d/"
+	apkInstalledPath  = "lib/apk/db/installed"
+
+	ecosystemGo = "Go"
+)
+
+// Package is a package installed in the image
+type Package struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Ecosystem string `json:"ecosystem"`
+	Location  string `json:"location"`
+}
+
+// Finding is a vulnerability affecting a package in the image
+type Finding struct {
+	ID           string   `json:"id"`
+	Aliases      []string `json:"aliases,omitempty"`
+	Summary      string   `json:"summary,omitempty"`
+	Severity     string   `json:"severity"`
+	Package      string   `json:"package"`
+	Version      string   `json:"version"`
+	FixedVersion string   `json:"fixedVersion,omitempty"`
+	Ecosystem    string   `json:"ecosystem"`
+	Location     string   `json:"location"`
+}
+
+// ScanResult contains the vulnerability scan results
+type ScanResult struct {
+	Image    string    `json:"image"`
+	Packages []Package `json:"packages"`
+	Findings []Finding `json:"findings"`
+}
+
+// CountAtLeast returns the number of findings with the severity equal to or higher than the threshold
+func (r *ScanResult) CountAtLeast(severity string) int {
+	var count int
+	for _, finding := range r.Findings {
+		if tools.SeverityRank(finding.Severity) >= tools.SeverityRank(severity) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// String returns the findings as a table with colors
+func (r *ScanResult) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("Scanned %d packages in %s\n\n", len(r.Packages), logs.Blue(r.Image)))
+
+	if len(r.Findings) == 0 {
+		sb.WriteString(logs.BoldGreen("✓ No vulnerabilities found\n"))
+		return sb.String()
+	}
+
+	sb.WriteString(fmt.Sprintf("%-10s %-20s %-30s %-24s %-24s %s\n", "SEVERITY", "ID", "PACKAGE", "VERSION", "FIXED", "LOCATION"))
+	sb.WriteString("---------- -------------------- ------------------------------ ------------------------ ------------------------ --------\n")
+	for _, finding := range r.Findings {
+		sb.WriteString(fmt.Sprintf("%s %-20s %-30s %-24s %-24s %s\n",
+			colorSeverity(fmt.Sprintf("%-10s", strings.ToUpper(finding.Severity)), finding.Severity),
+			finding.ID, finding.Package, finding.Version, finding.FixedVersion, finding.Location))
+	}
+
+	counts := make(map[string]int)
+	for _, finding := range r.Findings {
+		counts[finding.Severity]++
+	}
+
+	sb.WriteString(fmt.Sprintf("\nTotal: %d (critical: %d, high: %d, medium: %d, low: %d, unknown: %d)\n",
+		len(r.Findings), counts[tools.SeverityCritical], counts[tools.SeverityHigh],
+		counts[tools.SeverityMedium], counts[tools.SeverityLow], counts[tools.SeverityUnknown]))
+
+	return sb.String()
+}
+
+// colorSeverity colors the text according to the severity
+func colorSeverity(text, severity string) string {
+	switch severity {
+	case tools.SeverityCritical:
+		return logs.BoldRed(text)
+	case tools.SeverityHigh:
+		return logs.Red(text)
+	case tools.SeverityMedium:
+		return logs.Yellow(text)
+	case tools.SeverityLow:
+		return logs.Blue(text)
+	default:
+		return logs.Gray(text)
+	}
+}
+
+// ToJSON returns JSON representation of the scan result
+func (r *ScanResult) ToJSON() (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal scan result to JSON: %w", err)
+	}
+
+	return string(data), nil
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name           string      `json:"name"`
+			InformationURI string      `json:"informationUri"`
+			Rules          []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifRule struct {
+	ID               string            `json:"id"`
+	ShortDescription sarifMessage      `json:"shortDescription"`
+	HelpURI          string            `json:"helpUri"`
+	Properties       map[string]string `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+}
+
+// sarifSecuritySeverity maps severities to scores understood by code scanning tools
+var sarifSecuritySeverity = map[string]string{
+	tools.SeverityCritical: "9.5",
+	tools.SeverityHigh:     "8.0",
+	tools.SeverityMedium:   "5.5",
+	tools.SeverityLow:      "2.0",
+}
+
+// ToSARIF returns SARIF 2.1.0 representation of the scan result
+func (r *ScanResult) ToSARIF() (string, error) {
+	run := sarifRun{Results: []sarifResult{}}
+	run.Tool.Driver.Name = userAgent
+	run.Tool.Driver.InformationURI = "https://github.com/ipaqsa/artship"
+	run.Tool.Driver.Rules = []sarifRule{}
+
+	rules := make(map[string]bool)
+	for _, finding := range r.Findings {
+		if !rules[finding.ID] {
+			rules[finding.ID] = true
+
+			rule := sarifRule{
+				ID:               finding.ID,
+				ShortDescription: sarifMessage{Text: finding.Summary},
+				HelpURI:          "https://osv.dev/vulnerability/" + finding.ID,
+			}
+			if rule.ShortDescription.Text == "" {
+				rule.ShortDescription.Text = finding.ID
+			}
+			if score, ok := sarifSecuritySeverity[finding.Severity]; ok {
+				rule.Properties = map[string]string{"security-severity": score}
+			}
+
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+		}
+
+		level := "note"
+		switch tools.SeverityRank(finding.Severity) {
+		case 3, 4:
+			level = "error"
+		case 2:
+			level = "warning"
+		}
+
+		message := fmt.Sprintf("%s %s (%s) is affected by %s", finding.Package, finding.Version, finding.Ecosystem, finding.ID)
+		if finding.FixedVersion != "" {
+			message += fmt.Sprintf(", fixed in %s", finding.FixedVersion)
+		}
+
+		var location sarifLocation
+		location.PhysicalLocation.ArtifactLocation.URI = finding.Location
+
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    finding.ID,
+			Level:     level,
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{location},
+		})
+	}
+
+	data, err := json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal scan result to SARIF: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// Scan inventories packages in the image and matches them against the local OSV database
+func (c *Client) Scan(ctx context.Context, imageRef, dbPath string) (*ScanResult, error) {
+	if imageRef == "" {
+		return nil, fmt.Errorf("no image ref provided")
+	}
+
+	if dbPath == "" {
+		return nil, fmt.Errorf("no OSV database provided")
+	}
+
+	packages, err := c.inventory(ctx, imageRef)
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Found %d packages, loading the OSV database...", len(packages))
+
+	names := make(map[string]bool)
+	for _, pkg := range packages {
+		names[tools.EcosystemBase(pkg.Ecosystem)+"/"+pkg.Name] = true
+	}
+
+	entries, err := tools.LoadOSV(dbPath, func(ecosystem, name string) bool {
+		return names[tools.EcosystemBase(ecosystem)+"/"+name]
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("Loaded %d relevant OSV entries", len(entries))
+
+	byName := make(map[string][]*tools.OSVEntry)
+	for _, entry := range entries {
+		for _, affected := range entry.Affected {
+			byName[affected.Package.Name] = append(byName[affected.Package.Name], entry)
+		}
+	}
+
+	result := &ScanResult{
+		Image:    imageRef,
+		Packages: packages,
+		Findings: []Finding{},
+	}
+
+	seen := make(map[string]bool)
+	for _, pkg := range packages {
+		for _, entry := range byName[pkg.Name] {
+			key := entry.ID + "/" + pkg.Ecosystem + "/" + pkg.Name + "/" + pkg.Location
+			if seen[key] {
+				continue
+			}
+
+			finding, ok := matchOSVEntry(entry, pkg)
+			if !ok {
+				continue
+			}
+
+			seen[key] = true
+			result.Findings = append(result.Findings, finding)
+		}
+	}
+
+	sort.Slice(result.Findings, func(i, j int) bool {
+		a, b := result.Findings[i], result.Findings[j]
+		if rankA, rankB := tools.SeverityRank(a.Severity), tools.SeverityRank(b.Severity); rankA != rankB {
+			return rankA > rankB
+		}
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+
+		return a.ID < b.ID
+	})
+
+	return result, nil
+}
+
+// matchOSVEntry checks if the package is affected by the OSV entry
+func matchOSVEntry(entry *tools.OSVEntry, pkg Package) (Finding, bool) {
+	for i := range entry.Affected {
+		affected := &entry.Affected[i]
+		if affected.Package.Name != pkg.Name || !tools.MatchEcosystem(affected.Package.Ecosystem, pkg.Ecosystem) {
+			continue
+		}
+
+		ok, fixed := affected.Affects(pkg.Ecosystem, pkg.Version)
+		if !ok {
+			continue
+		}
+
+		return Finding{
+			ID:           entry.ID,
+			Aliases:      entry.Aliases,
+			Summary:      entry.Summary,
+			Severity:     entry.SeverityOf(affected),
+			Package:      pkg.Name,
+			Version:      pkg.Version,
+			FixedVersion: fixed,
+			Ecosystem:    pkg.Ecosystem,
+			Location:     pkg.Location,
+		}, true
+	}
+
+	return Finding{}, false
+}
+
+// inventory collects OS packages and Go modules from the image
+func (c *Client) inventory(ctx context.Context, imageRef string) ([]Package, error) {
+	img, err := c.extractImage(ctx, imageRef)
+	if err != nil {
+		return nil, err
+	}
+	defer img.Close()
+
+	var packages []Package
+	var osRelease []byte
+	dbs := make(map[string][]byte)
+
+	c.logger.Debug("Searching for packages...")
+	err = tools.WalkTar(img, func(r io.Reader, header *tar.Header) error {
+		if header.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		name := tools.CleanPath(header.Name)
+		switch {
+		case name == "etc/os-release" || (name == "usr/lib/os-release" && osRelease == nil):
+			osRelease, err = io.ReadAll(r)
+			if err != nil {
+				return fmt.Errorf("read '%s': %w", name, err)
+			}
+		case name == dpkgStatusPath || name == apkInstalledPath ||
+			(strings.HasPrefix(name, dpkgStatusDirPath) && !strings.HasSuffix(name, ".md5sums")):
+			dbs[name], err = io.ReadAll(r)
+			if err != nil {
+				return fmt.Errorf("read '%s': %w", name, err)
+			}
+		case header.Mode&0o111 != 0:
+			if bin, err := readGoBinary(r, header); err == nil {
+				packages = append(packages, goPackages(bin)...)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walk the image: %w", err)
+	}
+
+	osEcosystem := parseOSRelease(osRelease)
+	for name, db := range dbs {
+		if name == apkInstalledPath {
+			packages = append(packages, parseAPKInstalled(db, ecosystemOr(osEcosystem, "Alpine"), name)...)
+			continue
+		}
+
+		packages = append(packages, parseDpkgStatus(db, ecosystemOr(osEcosystem, "Debian"), name)...)
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		if packages[i].Location != packages[j].Location {
+			return packages[i].Location < packages[j].Location
+		}
+
+		return packages[i].Name < packages[j].Name
+	})
+
+	return packages, nil
+}
+
+// ecosystemOr returns the OS ecosystem if it is known, otherwise the fallback
+func ecosystemOr(ecosystem, fallback string) string {
+	if ecosystem == "" {
+		return fallback
+	}
+
+	return ecosystem
+}
+
+// parseOSRelease returns the OSV ecosystem of the distribution (e.g. 'Debian:12', 'Alpine:v3.18')
+func parseOSRelease(content []byte) string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(string(content), "\n") {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			fields[key] = strings.Trim(value, `"'`)
+		}
+	}
+
+	version := fields["VERSION_ID"]
+	switch fields["ID"] {
+	case "debian":
+		if major, _, _ := strings.Cut(version, "."); major != "" {
+			return "Debian:" + major
+		}
+		return "Debian"
+	case "ubuntu":
+		if version != "" {
+			return "Ubuntu:" + version
+		}
+		return "Ubuntu"
+	case "alpine":
+		parts := strings.Split(version, ".")
+		if len(parts) >= 2 {
+			return "Alpine:v" + parts[0] + "." + parts[1]
+		}
+		return "Alpine"
+	default:
+		return ""
+	}
+}
+
+// parseDpkgStatus parses the dpkg status database, packages are reported by source name as OSV does
+func parseDpkgStatus(content []byte, ecosystem, location string) []Package {
+	var packages []Package
+	for _, paragraph := range strings.Split(string(content), "\n\n") {
+		fields := parseControlFields(paragraph)
+		if fields["Package"] == "" || fields["Version"] == "" {
+			continue
+		}
+
+		if status, ok := fields["Status"]; ok && !strings.HasSuffix(status, " installed") {
+			continue
+		}
+
+		name, version := fields["Package"], fields["Version"]
+		if source := fields["Source"]; source != "" {
+			sourceName, sourceVersion, ok := strings.Cut(source, " ")
+			name = sourceName
+			if ok {
+				version = strings.Trim(sourceVersion, "()")
+			}
+		}
+
+		packages = append(packages, Package{
+			Name:      name,
+			Version:   version,
+			Ecosystem: ecosystem,
+			Location:  location,
+		})
+	}
+
+	return packages
+}
+
+// parseControlFields parses 'Key: value' fields of a Debian control paragraph
+func parseControlFields(paragraph string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(paragraph, "\n") {
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			fields[key] = strings.TrimSpace(value)
+		}
+	}
+
+	return fields
+}
+
+// parseAPKInstalled parses the apk installed database, packages are reported by origin name as OSV does
+func parseAPKInstalled(content []byte, ecosystem, location string) []Package {
+	var packages []Package
+	for _, record := range strings.Split(string(content), "\n\n") {
+		var name, version, origin string
+		for _, line := range strings.Split(record, "\n") {
+			key, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+
+			switch key {
+			case "P":
+				name = value
+			case "V":
+				version = value
+			case "o":
+				origin = value
+			}
+		}
+
+		if origin != "" {
+			name = origin
+		}
+
+		if name == "" || version == "" {
+			continue
+		}
+
+		packages = append(packages, Package{
+			Name:      name,
+			Version:   version,
+			Ecosystem: ecosystem,
+			Location:  location,
+		})
+	}
+
+	return packages
+}
+
+// goPackages returns the Go standard library and modules the binary was built with
+func goPackages(bin *GoBinary) []Package {
+	location := tools.CleanPath(bin.File)
+
+	var packages []Package
+	if version := goStdlibVersion(bin.GoVersion); version != "" {
+		packages = append(packages, Package{
+			Name:      "stdlib",
+			Version:   version,
+			Ecosystem: ecosystemGo,
+			Location:  location,
+		})
+	}
+
+	for _, mod := range append([]GoModule{bin.Main}, bin.Deps...) {
+		if mod.Replace != nil {
+			mod = *mod.Replace
+		}
+
+		// Local replacements and development builds have no comparable version
+		if mod.Version == "" || mod.Version == "(devel)" || path.IsAbs(mod.Path) || strings.HasPrefix(mod.Path, ".") {
+			continue
+		}
+
+		packages = append(packages, Package{
+			Name:      mod.Path,
+			Version:   mod.Version,
+			Ecosystem: ecosystemGo,
+			Location:  location,
+		})
+	}
+
+	return packages
+}
+
+// goStdlibVersion converts the Go toolchain version to semver (e.g. 'go1.22rc1' -> '1.22.0-rc.1')
+func goStdlibVersion(goVersion string) string {
+	version, _, _ := strings.Cut(strings.TrimPrefix(goVersion, "go"), " ")
+	if version == "" {
+		return ""
+	}
+
+	for _, pre := range []string{"rc", "beta"} {
+		if before, after, ok := strings.Cut(version, pre); ok {
+			if strings.Count(before, ".") == 1 {
+				before += ".0"
+			}
+
+			return before + "-" + pre + "." + after
+		}
+	}
+
+	return version
+}
@@ -0,0 +1,419 @@
+package client
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ipaqsa/artship/internal/logs"
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+// defaultMaxSecretFileSize is the size of the largest file scanned for secrets by default
+const defaultMaxSecretFileSize = 10 * 1024 * 1024
+
+// SecretRule describes how to detect a secret in file content
+type SecretRule struct {
+	ID          string   `yaml:"id"`
+	Description string   `yaml:"description"`
+	Regex       string   `yaml:"regex"`
+	Paths       []string `yaml:"paths,omitempty"`   // Path globs the rule applies to (all files if empty)
+	Entropy     float64  `yaml:"entropy,omitempty"` // Minimum Shannon entropy of the secret
+
+	re    *regexp.Regexp
+	paths []*regexp.Regexp
+}
+
+// SecretRules is the rule file format
+type SecretRules struct {
+	Rules      []SecretRule `yaml:"rules"`
+	Disable    []string     `yaml:"disable,omitempty"`    // IDs of default rules to disable
+	AllowPaths []string     `yaml:"allowPaths,omitempty"` // Path globs that are never scanned
+}
+
+// defaultSecretRules are used when no rule file overrides them.
+// The 'secret' group (or the whole match) is what gets reported and checked for entropy
+var defaultSecretRules = []SecretRule{
+	{
+		ID:          "private-key",
+		Description: "Private key",
+		Regex:       `-----BEGIN (?:(?:RSA|DSA|EC|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY(?: BLOCK)?-----`,
+	},
+	{
+		ID:          "aws-access-key-id",
+		Description: "AWS access key ID",
+		Regex:       `\b(?P<secret>(?:AKIA|ASIA|ABIA|ACCA)[A-Z0-9]{16})\b`,
+	},
+	{
+		ID:          "aws-secret-access-key",
+		Description: "AWS secret access key",
+		Regex:       `(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})`,
+	},
+	{
+		ID:          "github-token",
+		Description: "GitHub token",
+		Regex:       `\b(?P<secret>(?:gh[pousr]_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{82}))\b`,
+	},
+	{
+		ID:          "gitlab-token",
+		Description: "GitLab personal access token",
+		Regex:       `\b(?P<secret>glpat-[A-Za-z0-9_-]{20})\b`,
+	},
+	{
+		ID:          "slack-token",
+		Description: "Slack token",
+		Regex:       `\b(?P<secret>xox[baprs]-[A-Za-z0-9-]{10,})\b`,
+	},
+	{
+		ID:          "google-api-key",
+		Description: "Google API key",
+		Regex:       `\b(?P<secret>AIza[0-9A-Za-z_-]{35})\b`,
+	},
+	{
+		ID:          "stripe-secret-key",
+		Description: "Stripe secret key",
+		Regex:       `\b(?P<secret>(?:sk|rk)_live_[0-9A-Za-z]{24,})\b`,
+	},
+	{
+		ID:          "npmrc-auth",
+		Description: "npm registry credentials",
+		Regex:       `(?:_authToken|_auth|_password)\s*=\s*(?P<secret>\S+)`,
+		Paths:       []string{"**/.npmrc"},
+	},
+	{
+		ID:          "docker-config-auth",
+		Description: "Docker registry credentials",
+		Regex:       `"(?:auth|identitytoken)"\s*:\s*"(?P<secret>[A-Za-z0-9+/=._-]{8,})"`,
+		Paths:       []string{"**/.docker/config.json", "**/.dockercfg"},
+	},
+	{
+		ID:          "generic-secret",
+		Description: "High entropy secret assignment",
+		Regex:       `(?i)(?:api[_-]?key|secret|token|passw(?:or)?d|credentials?)["']?\s*[:=]\s*["']?(?P<secret>[A-Za-z0-9+/=_.\-]{16,})`,
+		Entropy:     4,
+	},
+}
+
+// SecretsOptions contains options for secret scanning
+type SecretsOptions struct {
+	RulesFile   string
+	MaxFileSize int64
+}
+
+// SecretFinding is a secret found in an image layer
+type SecretFinding struct {
+	RuleID      string `json:"rule"`
+	Description string `json:"description"`
+	LayerIndex  int    `json:"layerIndex"`
+	Layer       string `json:"layer"`
+	Path        string `json:"path"`
+	Line        int    `json:"line"`
+	Secret      string `json:"secret"`
+	Hidden      bool   `json:"hidden"` // Removed or overwritten by a later layer
+}
+
+// SecretsResult contains the secret scan results
+type SecretsResult struct {
+	Image    string          `json:"image"`
+	Layers   int             `json:"layers"`
+	Findings []SecretFinding `json:"findings"`
+}
+
+// String returns formatted findings with colors
+func (r *SecretsResult) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("Scanned %d layers of %s\n\n", r.Layers, logs.Blue(r.Image)))
+
+	if len(r.Findings) == 0 {
+		sb.WriteString(logs.BoldGreen("✓ No secrets found") + "\n")
+		return sb.String()
+	}
+
+	var hidden int
+	layer := -1
+	for _, finding := range r.Findings {
+		if finding.LayerIndex != layer {
+			layer = finding.LayerIndex
+			sb.WriteString(logs.BoldBlue(fmt.Sprintf("Layer %d (%s):", finding.LayerIndex, finding.Layer)) + "\n")
+		}
+
+		location := fmt.Sprintf("%s:%d", finding.Path, finding.Line)
+		sb.WriteString(fmt.Sprintf("  %s %s %s %s",
+			logs.Red("✗"), logs.Yellow(location), finding.Description, logs.Gray(finding.Secret)))
+
+		if finding.Hidden {
+			hidden++
+			sb.WriteString(logs.Red(" (removed in a later layer, still downloadable)"))
+		}
+
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n" + logs.BoldRed(fmt.Sprintf("Found %d secrets (%d hidden by later layers)", len(r.Findings), hidden)) + "\n")
+
+	return sb.String()
+}
+
+// ToJSON returns JSON representation of the secret scan result
+func (r *SecretsResult) ToJSON() (string, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("marshal secrets result to JSON: %w", err)
+	}
+
+	return string(data), nil
+}
+
+// Secrets scans every layer of the image for secrets, including files removed by later layers
+func (c *Client) Secrets(ctx context.Context, imageRef string, opts *SecretsOptions) (*SecretsResult, error) {
+	if imageRef == "" {
+		return nil, fmt.Errorf("no image ref provided")
+	}
+
+	if opts == nil {
+		opts = &SecretsOptions{}
+	}
+
+	maxFileSize := opts.MaxFileSize
+	if maxFileSize <= 0 {
+		maxFileSize = defaultMaxSecretFileSize
+	}
+
+	rules, allowPaths, err := loadSecretRules(opts.RulesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	layers, err := c.imageLayers(ctx, imageRef)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SecretsResult{
+		Image:    imageRef,
+		Layers:   len(layers),
+		Findings: []SecretFinding{},
+	}
+
+	changes := make([]*tools.LayerChanges, len(layers))
+	for i, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return nil, fmt.Errorf("get layer digest: %w", err)
+		}
+
+		c.logger.Debug("Scanning layer %d: %s", i, digest)
+
+		rc, err := layer.Uncompressed()
+		if err != nil {
+			return nil, fmt.Errorf("read the layer '%s': %w", digest, err)
+		}
+
+		changes[i], err = tools.ReadLayer(rc, func(r io.Reader, header *tar.Header) error {
+			if header.Typeflag != tar.TypeReg || header.Size == 0 {
+				return nil
+			}
+
+			name := tools.CleanPath(header.Name)
+			if header.Size > maxFileSize || matchAnyRegexp(allowPaths, name) {
+				c.logger.Debug("Skipping %s", name)
+				return nil
+			}
+
+			content, err := io.ReadAll(r)
+			if err != nil {
+				return fmt.Errorf("read '%s': %w", name, err)
+			}
+
+			for _, finding := range scanSecrets(rules, name, content) {
+				finding.LayerIndex = i
+				finding.Layer = digest.String()
+				result.Findings = append(result.Findings, finding)
+			}
+
+			return nil
+		})
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("scan the layer '%s': %w", digest, err)
+		}
+	}
+
+	for i := range result.Findings {
+		finding := &result.Findings[i]
+		for _, upper := range changes[finding.LayerIndex+1:] {
+			if upper.Hides(finding.Path) {
+				finding.Hidden = true
+				break
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// loadSecretRules compiles the default rules merged with the rules from the file
+func loadSecretRules(rulesFile string) ([]*SecretRule, []*regexp.Regexp, error) {
+	config := SecretRules{}
+	if rulesFile != "" {
+		raw, err := os.ReadFile(rulesFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read the rule file: %w", err)
+		}
+
+		if err = yaml.Unmarshal(raw, &config); err != nil {
+			return nil, nil, fmt.Errorf("parse the rule file '%s': %w", rulesFile, err)
+		}
+	}
+
+	disabled := make(map[string]bool)
+	for _, id := range config.Disable {
+		disabled[id] = true
+	}
+
+	// Custom rules override default rules with the same ID
+	for _, rule := range config.Rules {
+		disabled[rule.ID] = true
+	}
+
+	candidates := make([]SecretRule, 0, len(defaultSecretRules)+len(config.Rules))
+	for _, rule := range defaultSecretRules {
+		if !disabled[rule.ID] {
+			candidates = append(candidates, rule)
+		}
+	}
+	candidates = append(candidates, config.Rules...)
+
+	rules := make([]*SecretRule, 0, len(candidates))
+	for _, rule := range candidates {
+		compiled := rule
+		if err := compiled.compile(); err != nil {
+			return nil, nil, err
+		}
+
+		rules = append(rules, &compiled)
+	}
+
+	allowPaths, err := compileGlobs(config.AllowPaths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return rules, allowPaths, nil
+}
+
+// compile compiles the rule regex and path globs
+func (r *SecretRule) compile() error {
+	if r.ID == "" || r.Regex == "" {
+		return fmt.Errorf("secret rule must have an id and a regex")
+	}
+
+	var err error
+	if r.re, err = regexp.Compile(r.Regex); err != nil {
+		return fmt.Errorf("compile the rule '%s': %w", r.ID, err)
+	}
+
+	if r.paths, err = compileGlobs(r.Paths); err != nil {
+		return fmt.Errorf("compile the rule '%s': %w", r.ID, err)
+	}
+
+	return nil
+}
+
+// compileGlobs compiles glob patterns
+func compileGlobs(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := tools.CompileGlob(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		res = append(res, re)
+	}
+
+	return res, nil
+}
+
+// matchAnyRegexp checks if the path matches any of the compiled patterns
+func matchAnyRegexp(patterns []*regexp.Regexp, name string) bool {
+	for _, re := range patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scanSecrets applies the rules to text content line by line, binary content is skipped
+func scanSecrets(rules []*SecretRule, name string, content []byte) []SecretFinding {
+	if tools.IsBinary(content) {
+		return nil
+	}
+
+	var findings []SecretFinding
+	for _, rule := range rules {
+		if len(rule.paths) > 0 && !matchAnyRegexp(rule.paths, name) {
+			continue
+		}
+
+		for i, line := range strings.Split(string(content), "\n") {
+			for _, match := range rule.re.FindAllStringSubmatch(line, -1) {
+				secret := match[0]
+				if idx := rule.re.SubexpIndex("secret"); idx > 0 && match[idx] != "" {
+					secret = match[idx]
+				}
+
+				if rule.Entropy > 0 && entropy(secret) < rule.Entropy {
+					continue
+				}
+
+				findings = append(findings, SecretFinding{
+					RuleID:      rule.ID,
+					Description: rule.Description,
+					Path:        name,
+					Line:        i + 1,
+					Secret:      redact(secret),
+				})
+			}
+		}
+	}
+
+	return findings
+}
+
+// entropy returns the Shannon entropy of the string in bits per character
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+
+	var res float64
+	length := float64(len([]rune(s)))
+	for _, count := range counts {
+		p := float64(count) / length
+		res -= p * math.Log2(p)
+	}
+
+	return res
+}
+
+// redact hides most of the secret so the report itself does not leak it
+func redact(secret string) string {
+	if len(secret) <= 8 {
+		return strings.Repeat("*", len(secret))
+	}
+
+	return secret[:4] + strings.Repeat("*", min(len(secret)-4, 16))
+}
@@ -0,0 +1,122 @@
+package command
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ipaqsa/artship/internal/client"
+	"github.com/ipaqsa/artship/internal/logs"
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+var maxWasted string
+
+func init() {
+	analyzeCmd.Flags().StringVarP(&username, "username", "u", "", "Username for registry authentication")
+	analyzeCmd.Flags().StringVarP(&password, "password", "p", "", "Password for registry authentication")
+	analyzeCmd.Flags().StringVarP(&token, "token", "t", "", "Token for registry authentication")
+	analyzeCmd.Flags().StringVarP(&auth, "auth", "", "", "Auth for registry authentication")
+	analyzeCmd.Flags().BoolVarP(&insecure, "insecure", "k", false, "Allow insecure registry connections")
+	analyzeCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose debug output")
+	analyzeCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Output format (json)")
+	analyzeCmd.Flags().StringVar(&maxWasted, "max-wasted", "", "Exit with an error if wasted space exceeds this size or percentage (e.g. 50MB, 10%)")
+
+	rootCmd.AddCommand(analyzeCmd)
+}
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze <image>",
+	Short: "Analyze layer efficiency and wasted space of an OCI/Docker image",
+	Long: `Analyze walks each layer of an image separately and reports:
+- Per-layer size of added, changed and removed files
+- Bytes overwritten or deleted by later layers (wasted space)
+- Identical files stored in more than one layer
+- Overall efficiency: the share of stored bytes present in the final filesystem
+
+Use --max-wasted to fail CI jobs on bloated images.`,
+	Example: `  # Show the layer efficiency report
+  artship analyze myapp:latest
+
+  # Fail when more than 10% of the stored bytes are wasted
+  artship analyze myapp:latest --max-wasted 10%
+
+  # Produce JSON for further processing
+  artship analyze myapp:latest -o json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var limitBytes int64
+		var limitPercent float64
+		if maxWasted != "" {
+			var err error
+			if limitBytes, limitPercent, err = parseThreshold(maxWasted); err != nil {
+				return fmt.Errorf("invalid max wasted threshold: %w", err)
+			}
+		}
+
+		logger := logs.New(verbose)
+
+		cli := client.New(&client.Options{
+			Username: username,
+			Password: password,
+			Token:    token,
+			Auth:     auth,
+			Insecure: insecure,
+			Logger:   logger,
+		})
+
+		result, err := cli.Analyze(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to analyze image: %w", err)
+		}
+
+		switch outputFormat {
+		case "json":
+			jsonStr, err := result.ToJSON()
+			if err != nil {
+				return fmt.Errorf("failed to generate JSON output: %w", err)
+			}
+			fmt.Println(jsonStr)
+		case "":
+			fmt.Print(result.String())
+		default:
+			return fmt.Errorf("unsupported output format '%s'", outputFormat)
+		}
+
+		if maxWasted != "" {
+			exceeded := result.WastedBytes > limitBytes
+			if limitPercent > 0 {
+				exceeded = result.WastedPercent() > limitPercent
+			}
+
+			if exceeded {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("wasted space %s (%.1f%%) exceeds the limit of %s",
+					tools.FormatSize(result.WastedBytes), result.WastedPercent(), maxWasted)
+			}
+		}
+
+		return nil
+	},
+}
+
+// parseThreshold parses a size ('50MB') or a percentage ('10%') threshold
+func parseThreshold(s string) (int64, float64, error) {
+	if value, ok := strings.CutSuffix(strings.TrimSpace(s), "%"); ok {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || percent <= 0 {
+			return 0, 0, fmt.Errorf("invalid percentage '%s'", s)
+		}
+
+		return 0, percent, nil
+	}
+
+	size, err := tools.ParseSize(s)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return size, 0, nil
+}
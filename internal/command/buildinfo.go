@@ -0,0 +1,77 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ipaqsa/artship/internal/client"
+	"github.com/ipaqsa/artship/internal/logs"
+)
+
+func init() {
+	buildInfoCmd.Flags().StringVarP(&username, "username", "u", "", "Username for registry authentication")
+	buildInfoCmd.Flags().StringVarP(&password, "password", "p", "", "Password for registry authentication")
+	buildInfoCmd.Flags().StringVarP(&token, "token", "t", "", "Token for registry authentication")
+	buildInfoCmd.Flags().StringVarP(&auth, "auth", "", "", "Auth for registry authentication")
+	buildInfoCmd.Flags().BoolVarP(&insecure, "insecure", "k", false, "Allow insecure registry connections")
+	buildInfoCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose debug output")
+	buildInfoCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Output format (json)")
+
+	rootCmd.AddCommand(buildInfoCmd)
+}
+
+var buildInfoCmd = &cobra.Command{
+	Use:   "buildinfo <image> [path...]",
+	Short: "Show Go build information of binaries inside an OCI/Docker image",
+	Long: `Buildinfo locates Go executables in an OCI/Docker image and prints the
+build information embedded by the Go toolchain: Go version, main module,
+dependencies, VCS revision and build settings such as -ldflags.
+
+Without paths every executable file in the image is inspected. Paths are
+matched the same way as artifacts in other commands (exact path, file name,
+directory or glob pattern).`,
+	Example: `  # Show build info of all Go binaries in an image
+  artship buildinfo ghcr.io/ipaqsa/artship:latest
+
+  # Show build info of a specific binary
+  artship buildinfo myapp:latest /app/bin/server
+
+  # Output as JSON
+  artship buildinfo myapp:latest -o json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := logs.New(verbose)
+
+		cli := client.New(&client.Options{
+			Username: username,
+			Password: password,
+			Token:    token,
+			Auth:     auth,
+			Insecure: insecure,
+			Logger:   logger,
+		})
+
+		binaries, err := cli.BuildInfo(cmd.Context(), args[0], args[1:])
+		if err != nil {
+			return fmt.Errorf("failed to get build info: %w", err)
+		}
+
+		if outputFormat == "json" {
+			jsonStr, err := binaries.ToJSON()
+			if err != nil {
+				return fmt.Errorf("failed to generate JSON output: %w", err)
+			}
+			fmt.Println(jsonStr)
+
+			return nil
+		}
+
+		logger.Info("")
+		logger.Info("%s", logs.BoldBlue("Go build information:"))
+		logger.Info("%s", logs.Gray("─────────────────────────────────────────────────────────────"))
+		logger.Info("%s", binaries.String())
+
+		return nil
+	},
+}
@@ -16,6 +16,8 @@ func init() {
 	catCmd.Flags().StringVarP(&auth, "auth", "", "", "Auth for registry authentication")
 	catCmd.Flags().BoolVarP(&insecure, "insecure", "k", false, "Allow insecure registry connections")
 	catCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose debug output")
+	catCmd.Flags().StringVarP(&layer, "layer", "l", "", "Read the file from a single layer: digest or index, negative from the top layer")
+	catCmd.Flags().BoolVar(&regex, "regex", false, "Treat the artifact as a regular expression matched against image paths")
 
 	rootCmd.AddCommand(catCmd)
 }
@@ -25,12 +27,25 @@ var catCmd = &cobra.Command{
 	Short: "Show the content of an artifact from an OCI/Docker image",
 	Long: `Cat prints the content of a specific file artifact from an OCI/Docker image
 to stdout. This is useful for examining configuration files, scripts, or other
-text-based artifacts without extracting them to the filesystem.`,
+text-based artifacts without extracting them to the filesystem.
+
+The artifact can be a path, a file name, a glob pattern or, with --regex,
+a regular expression. The first matching regular file is printed.
+
+With --layer the file is read from a single layer (by digest or by index,
+where -1 is the top layer) instead of the merged image, which recovers files
+that later layers deleted or overwrote.`,
 	Example: `  # Show content of a configuration file
   artship cat nginx:latest /etc/nginx/nginx.conf
   
   # Show content of passwd file
-  artship cat alpine:latest /etc/passwd`,
+  artship cat alpine:latest /etc/passwd
+
+  # Show the first matching file
+  artship cat nginx:latest 'etc/nginx/conf.d/*.conf'
+
+  # Show a file as the base layer shipped it
+  artship cat myapp:latest /etc/os-release --layer 0`,
 	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		logger := logs.New(verbose)
@@ -44,7 +59,7 @@ text-based artifacts without extracting them to the filesystem.`,
 			Logger:   logger,
 		})
 
-		content, err := cli.Cat(cmd.Context(), args[0], args[1])
+		content, err := cli.Cat(cmd.Context(), args[0], layer, args[1], regex)
 		if err != nil {
 			return fmt.Errorf("failed to cat artifact: %w", err)
 		}
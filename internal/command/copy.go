@@ -2,6 +2,7 @@ package command
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 
@@ -13,12 +14,34 @@ var (
 	artifacts  []string
 	output     string
 	extractTar bool
+	withDeps   bool
+	regex      bool
+
+	preservePaths   bool
+	stripComponents int
+
+	checksums     []string
+	checksumsFile string
+	emitChecksums bool
 )
 
 func init() {
-	copyCmd.Flags().StringSliceVarP(&artifacts, "artifact", "a", []string{}, "Artifact names to extract (files or directories, required)")
-	copyCmd.Flags().StringVarP(&output, "output", "o", ".", "Target path for the extracted artifacts (required)")
+	copyCmd.Flags().StringSliceVarP(&artifacts, "artifact", "a", []string{}, "Artifacts to extract: names, directories, glob patterns or 'src=dst' mappings (required)")
+	copyCmd.Flags().StringVarP(&output, "output", "o", ".", "Target path for the extracted artifacts, '-' writes a tar stream to stdout (required)")
 	copyCmd.Flags().BoolVar(&extractTar, "tar", false, "Extract the entire image as a tar archive")
+	copyCmd.Flags().BoolVar(&regex, "regex", false, "Treat artifacts as regular expressions matched against image paths")
+	copyCmd.Flags().BoolVar(&preservePaths, "preserve-paths", false, "Keep image paths of the artifacts inside the output directory")
+	copyCmd.Flags().IntVar(&stripComponents, "strip-components", 0, "Remove the number of leading components from image paths, implies --preserve-paths")
+	copyCmd.Flags().StringSliceVar(&checksums, "sha256", nil, "Expected SHA256 of an image file as 'path=hex', the file is not written on mismatch")
+	copyCmd.Flags().StringVar(&checksumsFile, "checksums-file", "", "SHA256SUMS file with expected checksums by image path or path relative to the output")
+	copyCmd.Flags().BoolVar(&emitChecksums, "emit-checksums", false, "Write a SHA256SUMS file for the copied files into the output directory")
+	copyCmd.Flags().StringVar(&maxTotalSize, "max-total-size", "", "Abort when the copied files exceed this total size (e.g. 10GB)")
+	copyCmd.Flags().StringVar(&maxFileSize, "max-file-size", "", "Abort when a single file exceeds this size (e.g. 1GB)")
+	copyCmd.Flags().Int64Var(&maxFiles, "max-files", 0, "Abort when more files, directories and links are copied")
+	copyCmd.Flags().IntVar(&maxDepth, "max-depth", 0, "Abort when an image path has more components")
+	copyCmd.Flags().StringVarP(&layer, "layer", "l", "", "Copy from a single layer: digest or index, negative from the top layer")
+	copyCmd.Flags().BoolVar(&strict, "strict", false, "Fail on links leaving the output directory, including absolute symlinks")
+	copyCmd.Flags().BoolVar(&withDeps, "with-deps", false, "Copy shared library dependencies of ELF binaries, preserving the image layout")
 	copyCmd.Flags().StringVarP(&username, "username", "u", "", "Username for registry authentication")
 	copyCmd.Flags().StringVarP(&password, "password", "p", "", "Password for registry authentication")
 	copyCmd.Flags().StringVarP(&token, "token", "t", "", "Token for registry authentication")
@@ -27,6 +50,18 @@ func init() {
 	copyCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose debug output")
 
 	copyCmd.MarkFlagsMutuallyExclusive("artifact", "tar")
+	copyCmd.MarkFlagsMutuallyExclusive("with-deps", "tar")
+	copyCmd.MarkFlagsMutuallyExclusive("with-deps", "preserve-paths")
+	copyCmd.MarkFlagsMutuallyExclusive("with-deps", "strip-components")
+	copyCmd.MarkFlagsMutuallyExclusive("with-deps", "sha256")
+	copyCmd.MarkFlagsMutuallyExclusive("with-deps", "checksums-file")
+	copyCmd.MarkFlagsMutuallyExclusive("with-deps", "emit-checksums")
+	copyCmd.MarkFlagsMutuallyExclusive("with-deps", "layer")
+	copyCmd.MarkFlagsMutuallyExclusive("tar", "layer")
+	copyCmd.MarkFlagsMutuallyExclusive("tar", "max-total-size")
+	copyCmd.MarkFlagsMutuallyExclusive("tar", "max-file-size")
+	copyCmd.MarkFlagsMutuallyExclusive("tar", "max-files")
+	copyCmd.MarkFlagsMutuallyExclusive("tar", "max-depth")
 
 	rootCmd.AddCommand(copyCmd)
 }
@@ -39,7 +74,63 @@ specific files or directories to target paths on the local filesystem.
 
 Multiple artifacts can be extracted in a single operation by specifying
 multiple --artifact flags. Alternatively, use --tar to extract the entire
-image as a tar archive.`,
+image as a tar archive.
+
+An artifact is an exact path, a file name, a directory or a glob pattern
+where '**' matches any number of directories ('usr/lib/**/*.so*'). With
+--regex artifacts are regular expressions searched in image paths. The paths
+matched by each artifact are reported, and the command fails if any artifact
+matches nothing.
+
+By default every matched file is written to the output directory under its
+base name. With --preserve-paths the image paths are kept inside the output
+directory, and --strip-components N removes N leading components from them
+like tar does. Two files written to the same path are reported as an error
+instead of overwriting each other.
+
+An artifact written as 'src=dst' is copied to its own destination instead of
+--output, so one invocation can place several artifacts. A file or directory
+is renamed to dst (or copied into it if dst is a directory or ends with '/'),
+and a source ending with '/' copies the directory contents into dst. All
+mappings are validated before the image is downloaded.
+
+With --output - the matched entries are written to stdout as a tar stream
+with their image paths (after --strip-components), modes, owners and links,
+ready to be piped into tar. Log messages go to stderr. --tar --output -
+streams the whole image the same way.
+
+--sha256 path=hex and --checksums-file (the sha256sum format, paths in the
+image) verify the content of image files while it is streamed out of the
+image. A mismatching file is never written: the content goes to a temporary
+file first, and the copy fails. Every --sha256 path must be copied, entries
+of the checksums file apply only to copied files. --emit-checksums writes a
+SHA256SUMS file for the copied files into the output directory, with paths
+relative to it. Entries of --checksums-file match image paths or, like the
+emitted file, paths relative to the output directory, so the SHA256SUMS of a
+previous copy verifies a new one with the same artifacts.
+
+--max-total-size, --max-file-size, --max-files and --max-depth bound what
+the copy writes. It aborts before the first entry above a limit and removes
+the files it created.
+
+With --layer artifacts are copied from a single layer (by digest or by index,
+where -1 is the top layer) instead of the merged image, so a file that a later
+layer deleted or overwrote can be recovered.
+
+Paths are resolved inside the output directory, so a symlink copied before,
+such as 'lib -> /etc', never leads a later file outside of it. --strict fails
+the copy on symlinks with absolute targets or targets leaving the output, and
+on hardlinks leaving it.
+
+Hardlinks are linked to the copy of their target. If the target is not
+selected, its content is read from the image again and copied to the link.
+
+With --with-deps the shared libraries required by ELF binaries (DT_NEEDED,
+resolved via RPATH/RUNPATH, the image's ld.so.conf and default library
+directories) are copied as well. The artifacts and their dependencies keep
+their image paths inside the output directory, including symlinks. The
+layout is written to a directory, so --with-deps can not be used with
+--output -.`,
 	Example: `  # Copy a single binary from nginx image
   artship cp nginx:latest --artifact nginx --output /usr/local/bin
 
@@ -49,6 +140,34 @@ image as a tar archive.`,
   # Copy directories and files
   artship cp myapp:latest --artifact /app/bin --artifact /app/config --output ./local
 
+  # Copy all shared libraries below usr/lib
+  artship cp debian:12 -a 'usr/lib/**/*.so*' -o ./libs
+
+  # Copy files selected by a regular expression
+  artship cp myapp:latest -a '^app/bin/[^/]+$' --regex -o ./bin
+
+  # Copy a directory keeping its layout: ./conf/nginx/conf.d/default.conf
+  artship cp nginx:latest -a etc/nginx --strip-components 1 -o ./conf
+
+  # Place artifacts at distinct destinations
+  artship cp nginx:1.25 -a usr/sbin/nginx=./bin/nginx-1.25 -a etc/nginx/=./conf/
+
+  # Stream artifacts into another directory or host without touching the disk
+  artship cp nginx:latest -a etc/nginx -o - | tar -x -C /opt
+  artship cp nginx:latest -a etc/nginx -o - | ssh host tar -x -C /opt
+
+  # Copy a binary only if it is the expected build
+  artship cp myapp:1.0 -a app/bin/server=./server --sha256 app/bin/server=3a7bd3e2360a...
+
+  # Copy and record checksums for later verification with 'sha256sum -c'
+  artship cp myapp:1.0 -a app/bin -o ./bin/ --emit-checksums
+
+  # Recover a file that a later layer deleted
+  artship cp myapp:latest -a etc/app/secret.conf --layer 2 -o ./recovered
+
+  # Copy a binary together with its shared libraries
+  artship cp nginx:latest -a /usr/sbin/nginx --with-deps -o ./rootfs
+
   # Extract entire image as tar archive
   artship cp nginx:latest --tar --output ./nginx.tar
 
@@ -57,6 +176,10 @@ image as a tar archive.`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		logger := logs.New(verbose)
+		if output == "-" {
+			// Keep stdout clean for the tar stream
+			logger.SetOutput(os.Stderr)
+		}
 
 		cli := client.New(&client.Options{
 			Username: username,
@@ -75,7 +198,26 @@ image as a tar archive.`,
 			if len(artifacts) == 0 {
 				return fmt.Errorf("no artifacts specified (use --artifact or --tar)")
 			}
-			if err := cli.Copy(cmd.Context(), args[0], artifacts, output); err != nil {
+			if stripComponents < 0 {
+				return fmt.Errorf("--strip-components must not be negative")
+			}
+			limits, err := parseLimits()
+			if err != nil {
+				return err
+			}
+			opts := &client.CopyOptions{
+				WithDeps:        withDeps,
+				Regex:           regex,
+				PreservePaths:   preservePaths,
+				StripComponents: stripComponents,
+				SHA256:          checksums,
+				ChecksumsFile:   checksumsFile,
+				EmitChecksums:   emitChecksums,
+				Limits:          limits,
+				Layer:           layer,
+				Strict:          strict,
+			}
+			if err := cli.Copy(cmd.Context(), args[0], artifacts, output, opts); err != nil {
 				return fmt.Errorf("failed to copy artifacts: %w", err)
 			}
 		}
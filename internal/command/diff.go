@@ -2,6 +2,9 @@ package command
 
 import (
 	"fmt"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -13,6 +16,20 @@ var (
 	outputFormat  string
 	showUnchanged bool
 	diffFilter    string
+	diffContent   bool
+	diffPatch     []string
+	diffConfig    bool
+
+	diffFailOn          []string
+	diffMaxSizeIncrease string
+	diffIgnore          []string
+	diffExitCode        bool
+)
+
+// Exit codes of diff with a policy, errors exit with 1
+const (
+	exitCodeDifferences    = 2 // Differences found within the policy
+	exitCodePolicyViolated = 3
 )
 
 func init() {
@@ -22,29 +39,74 @@ func init() {
 	diffCmd.Flags().StringVarP(&auth, "auth", "", "", "Auth for registry authentication")
 	diffCmd.Flags().BoolVarP(&insecure, "insecure", "k", false, "Allow insecure registry connections")
 	diffCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose debug output")
-	diffCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Output format (json)")
+	diffCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Output format (json, markdown, html, junit)")
 	diffCmd.Flags().BoolVar(&showUnchanged, "show-unchanged", false, "Show unchanged files in the output")
 	diffCmd.Flags().StringVarP(&diffFilter, "filter", "f", "", "Filter diff results (added, removed, modified, all)")
+	diffCmd.Flags().BoolVar(&diffContent, "content", false, "Compare file contents by SHA256, not only size and permissions")
+	diffCmd.Flags().StringSliceVar(&diffPatch, "patch", nil, "Print a git-compatible unified diff of modified files, optionally limited to path globs (--patch='etc/**')")
+	diffCmd.Flags().Lookup("patch").NoOptDefVal = "**"
+	diffCmd.Flags().BoolVar(&diffConfig, "config", false, "Compare image configuration and metadata instead of filesystems")
+
+	diffCmd.Flags().StringSliceVar(&diffFailOn, "fail-on", nil, "Violate the policy when files are added, removed or modified (e.g. added,removed)")
+	diffCmd.Flags().StringVar(&diffMaxSizeIncrease, "max-size-increase", "", "Violate the policy when files grow by more than this size or percentage (e.g. 10MB, 5%)")
+	diffCmd.Flags().StringSliceVar(&diffIgnore, "ignore", nil, "Ignore files matching path globs (e.g. 'var/cache/**')")
+	diffCmd.Flags().BoolVar(&diffExitCode, "exit-code", false, "Exit with 2 when there are differences, implied by policy flags")
+
+	diffCmd.MarkFlagsMutuallyExclusive("config", "patch")
+	diffCmd.MarkFlagsMutuallyExclusive("config", "content")
+	diffCmd.MarkFlagsMutuallyExclusive("config", "fail-on")
+	diffCmd.MarkFlagsMutuallyExclusive("config", "max-size-increase")
+	diffCmd.MarkFlagsMutuallyExclusive("config", "ignore")
+	diffCmd.MarkFlagsMutuallyExclusive("config", "exit-code")
 
 	rootCmd.AddCommand(diffCmd)
 }
 
 var diffCmd = &cobra.Command{
-	Use:   "diff <image1> <image2>",
+	Use:   "diff <image1|dir:path> <image2|dir:path>",
 	Short: "Show file differences between two OCI/Docker images",
 	Long: `Diff compares two OCI/Docker images and shows the differences in their filesystems.
 
 The output includes:
 - Added files (files that exist only in image2)
 - Removed files (files that exist only in image1)
-- Modified files (files that exist in both but have different size or permissions)
+- Modified files (files that exist in both but have different size, permissions,
+  owner, symlink target or, with --content, content)
+
+With --content regular files present in both images are hashed while walking
+the layers. Files coming from a layer shared by both images are identical and
+are not read.
+
+With --patch a unified diff of modified text files is printed instead of the
+summary. Binary files are reported as "Binary files ... differ" and permission
+changes as git mode lines, so the output can be applied with 'git apply' inside
+a directory extracted from image1.
+
+Either side can be a local directory written as 'dir:/path'. Files on disk
+are compared by size, permissions, symlink target and, with --content, SHA256.
+
+Files matching --ignore globs are left out of the comparison, e.g. caches
+or timestamps that differ on every build.
+
+Diff exits with 0 by default. With --exit-code, --fail-on or --max-size-increase
+it can gate releases using distinct exit codes:
+- 0: no differences
+- 2: differences found within the policy
+- 3: policy violated (the violations are printed to stderr)
+- 1: the comparison failed
+
+With --config the image configuration is compared field by field instead:
+environment variables, labels, annotations, exposed ports, entrypoint, cmd,
+user, working directory, platform, layer count and total size.
 
 Results are displayed with color-coded output by default:
 - Green (+) for added files
 - Red (-) for removed files
 - Yellow (~) for modified files
 
-Use -o json flag for machine-readable JSON output.`,
+Use -o json flag for machine-readable JSON output. Reports for code review
+and CI are available as -o markdown (collapsible pull request summary with
+top size changes and added/removed executables), -o html and -o junit.`,
 	Example: `  # Compare two versions of the same image
   artship diff nginx:1.24 nginx:1.25
 
@@ -54,17 +116,45 @@ Use -o json flag for machine-readable JSON output.`,
   # Output as JSON
   artship diff nginx:latest nginx:alpine -o json
 
+  # Post a summary to a pull request
+  artship diff myapp:main myapp:pr-42 -o markdown > diff.md
+
   # Show unchanged files
   artship diff redis:7.0 redis:7.2 --show-unchanged
 
   # Compare images from private registry
   artship diff registry.io/app:v1 registry.io/app:v2 -u user -p pass
 
+  # Detect content changes that keep the file size
+  artship diff myapp:v1 myapp:v2 --content
+
+  # Show what changed inside modified configuration files
+  artship diff myapp:v1 myapp:v2 --patch='etc/**'
+
+  # Compare environment, entrypoint, labels and other configuration
+  artship diff myapp:v1 myapp:v2 --config
+
+  # Verify an extracted image against the original
+  artship diff myapp:v1 dir:./extracted --content
+
+  # Fail a release when files are removed or the image grows by more than 5%
+  artship diff myapp:v1 myapp:v2 --fail-on removed --max-size-increase 5% --ignore 'var/cache/**'
+
   # Filter to show only added files
   artship diff node:18 node:20 --filter added`,
 	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		policy, err := parseDiffPolicy()
+		if err != nil {
+			return err
+		}
+
 		logger := logs.New(verbose)
+		patch := cmd.Flags().Changed("patch")
+		if patch || outputFormat != "" {
+			// Keep stdout clean for piping the patch or the report
+			logger.SetOutput(os.Stderr)
+		}
 
 		cli := client.New(&client.Options{
 			Username: username,
@@ -75,37 +165,133 @@ Use -o json flag for machine-readable JSON output.`,
 			Logger:   logger,
 		})
 
+		if diffConfig {
+			return runConfigDiff(cmd, cli, args[0], args[1])
+		}
+
 		// Perform diff
-		result, err := cli.Diff(cmd.Context(), args[0], args[1], showUnchanged)
+		result, err := cli.Diff(cmd.Context(), args[0], args[1], &client.DiffOptions{
+			IncludeUnchanged: showUnchanged,
+			Content:          diffContent,
+			Patch:            patch,
+			PatchPaths:       diffPatch,
+			Ignore:           diffIgnore,
+		})
 		if err != nil {
 			return fmt.Errorf("failed to compare images: %w", err)
 		}
 
+		// The policy applies to all differences, not only the filtered ones
+		violations := result.CheckPolicy(policy)
+		changed := result.HasChanges()
+
 		// Apply filter if specified
 		if diffFilter != "" {
 			result = filterDiffResult(result, diffFilter)
 		}
 
 		// Output results
-		if outputFormat == "json" {
+		switch {
+		case outputFormat == "json":
 			jsonStr, err := result.ToJSON()
 			if err != nil {
 				return fmt.Errorf("failed to generate JSON output: %w", err)
 			}
 			fmt.Println(jsonStr)
-		} else {
+		case outputFormat == "markdown":
+			fmt.Print(result.ToMarkdown())
+		case outputFormat == "html":
+			html, err := result.ToHTML()
+			if err != nil {
+				return fmt.Errorf("failed to generate HTML output: %w", err)
+			}
+			fmt.Print(html)
+		case outputFormat == "junit":
+			junit, err := result.ToJUnit()
+			if err != nil {
+				return fmt.Errorf("failed to generate JUnit output: %w", err)
+			}
+			fmt.Println(junit)
+		case outputFormat != "":
+			return fmt.Errorf("unsupported output format '%s'", outputFormat)
+		case patch:
+			fmt.Print(result.Patch)
+		default:
 			fmt.Print(result.String(showUnchanged))
 		}
 
+		if !diffExitCode && len(policy.FailOn) == 0 && diffMaxSizeIncrease == "" {
+			return nil
+		}
+
+		cmd.SilenceUsage = true
+		switch {
+		case len(violations) > 0:
+			return &ExitError{
+				Code: exitCodePolicyViolated,
+				Err:  fmt.Errorf("diff policy violated: %s", strings.Join(violations, "; ")),
+			}
+		case changed:
+			cmd.SilenceErrors = true
+			return &ExitError{Code: exitCodeDifferences}
+		}
+
 		return nil
 	},
 }
 
+// parseDiffPolicy builds the diff policy from the flags
+func parseDiffPolicy() (*client.DiffPolicy, error) {
+	policy := &client.DiffPolicy{}
+
+	statuses := []client.FileStatus{client.FileStatusAdded, client.FileStatusRemoved, client.FileStatusModified}
+	for _, value := range diffFailOn {
+		status := client.FileStatus(strings.TrimSpace(value))
+		if !slices.Contains(statuses, status) {
+			return nil, fmt.Errorf("invalid --fail-on value '%s', expected added, removed or modified", value)
+		}
+
+		policy.FailOn = append(policy.FailOn, status)
+	}
+
+	if diffMaxSizeIncrease != "" {
+		var err error
+		policy.MaxSizeIncrease, policy.MaxSizeIncreasePercent, err = parseThreshold(diffMaxSizeIncrease)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max size increase: %w", err)
+		}
+	}
+
+	return policy, nil
+}
+
+// runConfigDiff compares and prints the image configurations
+func runConfigDiff(cmd *cobra.Command, cli *client.Client, image1Ref, image2Ref string) error {
+	result, err := cli.DiffConfig(cmd.Context(), image1Ref, image2Ref)
+	if err != nil {
+		return fmt.Errorf("failed to compare image configs: %w", err)
+	}
+
+	if outputFormat == "json" {
+		jsonStr, err := result.ToJSON()
+		if err != nil {
+			return fmt.Errorf("failed to generate JSON output: %w", err)
+		}
+		fmt.Println(jsonStr)
+	} else {
+		fmt.Print(result.String())
+	}
+
+	return nil
+}
+
 // filterDiffResult filters the diff result based on the specified filter
 func filterDiffResult(result *client.DiffResult, filter string) *client.DiffResult {
 	filtered := &client.DiffResult{
 		SourceImage: result.SourceImage,
 		TargetImage: result.TargetImage,
+		SourceSize:  result.SourceSize,
+		Violations:  result.Violations,
 	}
 
 	switch filter {
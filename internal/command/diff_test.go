@@ -0,0 +1,94 @@
+package command
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// exitCode returns the code the process exits with for the error of a command, as main does
+func exitCode(err error) int {
+	var exitErr *ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		return exitErr.Code
+	case err != nil:
+		return 1
+	}
+
+	return 0
+}
+
+// runDiff runs the diff command with fresh flags, its output is discarded
+func runDiff(t *testing.T, args ...string) error {
+	t.Helper()
+
+	diffFailOn, diffMaxSizeIncrease, diffIgnore, diffExitCode = nil, "", nil, false
+	outputFormat, diffFilter, diffContent, diffConfig = "", "", false, false
+	diffCmd.SilenceUsage, diffCmd.SilenceErrors = false, false
+
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = devNull
+	defer func() {
+		os.Stdout = stdout
+		devNull.Close()
+	}()
+
+	rootCmd.SetOut(io.Discard)
+	rootCmd.SetErr(io.Discard)
+	rootCmd.SetArgs(append([]string{"diff"}, args...))
+
+	return rootCmd.Execute()
+}
+
+func TestDiffExitCodes(t *testing.T) {
+	base := t.TempDir()
+	files := map[string]map[string]string{
+		"v1":   {"etc/app.conf": "port=80\n"},
+		"same": {"etc/app.conf": "port=80\n"},
+		"v2":   {"etc/app.conf": "port=80\n", "usr/bin/tool": "#!/bin/sh\n"},
+	}
+	for dir, content := range files {
+		for name, body := range content {
+			p := filepath.Join(base, dir, filepath.FromSlash(name))
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	dir := func(name string) string { return "dir:" + filepath.Join(base, name) }
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{name: "differences without a policy", args: []string{dir("v1"), dir("v2")}, want: 0},
+		{name: "no differences", args: []string{dir("v1"), dir("same"), "--exit-code"}, want: 0},
+		{name: "differences", args: []string{dir("v1"), dir("v2"), "--exit-code"}, want: exitCodeDifferences},
+		{name: "differences within the policy", args: []string{dir("v1"), dir("v2"), "--fail-on", "removed"}, want: exitCodeDifferences},
+		{name: "ignored differences", args: []string{dir("v1"), dir("v2"), "--fail-on", "added", "--ignore", "usr/**"}, want: 0},
+		{name: "added files violate the policy", args: []string{dir("v1"), dir("v2"), "--fail-on", "added"}, want: exitCodePolicyViolated},
+		{name: "size increase violates the policy", args: []string{dir("v1"), dir("v2"), "--max-size-increase", "5B"}, want: exitCodePolicyViolated},
+		{name: "failed comparison", args: []string{dir("v1"), dir("missing"), "--exit-code"}, want: 1},
+		{name: "invalid policy", args: []string{dir("v1"), dir("v2"), "--fail-on", "renamed"}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runDiff(t, tt.args...)
+			if got := exitCode(err); got != tt.want {
+				t.Errorf("exit code %d (%v), want %d", got, err, tt.want)
+			}
+		})
+	}
+}
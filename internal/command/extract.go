@@ -2,11 +2,31 @@ package command
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 
 	"github.com/ipaqsa/artship/internal/client"
 	"github.com/ipaqsa/artship/internal/logs"
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+var (
+	preserve []string
+	mapUID   []string
+	mapGID   []string
+	strict   bool
+
+	include []string
+	exclude []string
+
+	devices         bool
+	devicesManifest string
+
+	maxTotalSize string
+	maxFileSize  string
+	maxFiles     int64
+	maxDepth     int
 )
 
 func init() {
@@ -17,6 +37,21 @@ func init() {
 	extractCmd.Flags().StringVarP(&auth, "auth", "", "", "Auth for registry authentication")
 	extractCmd.Flags().BoolVarP(&insecure, "insecure", "k", false, "Allow insecure registry connections")
 	extractCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose debug output")
+	extractCmd.Flags().StringSliceVar(&preserve, "preserve", nil, "Restore metadata from the image: owner, time, xattrs or all")
+	extractCmd.Flags().StringSliceVar(&mapUID, "map-uid", nil, "Map file owners: 'UID' for all files or 'FROM:TO', implies --preserve owner")
+	extractCmd.Flags().BoolVar(&strict, "strict", false, "Fail on entries and links leaving the output directory, including absolute symlinks")
+	extractCmd.Flags().StringSliceVar(&mapGID, "map-gid", nil, "Map file groups: 'GID' for all files or 'FROM:TO', implies --preserve owner")
+
+	extractCmd.Flags().StringVarP(&layer, "layer", "l", "", "Extract a single layer: digest or index, negative from the top layer")
+	extractCmd.Flags().StringSliceVar(&include, "include", nil, "Extract only paths matching these names or globs (e.g. 'usr/share/zoneinfo/**')")
+	extractCmd.Flags().StringSliceVar(&exclude, "exclude", nil, "Skip paths matching these names or globs (e.g. '**/*.pyc')")
+
+	extractCmd.Flags().BoolVar(&devices, "devices", false, "Create device nodes and FIFOs, recording the ones that need privileges in a manifest")
+	extractCmd.Flags().StringVar(&devicesManifest, "devices-manifest", "", "Script recreating device nodes that need privileges (default <output>.devices.sh)")
+	extractCmd.Flags().StringVar(&maxTotalSize, "max-total-size", "", "Abort when the extracted files exceed this total size (e.g. 10GB)")
+	extractCmd.Flags().StringVar(&maxFileSize, "max-file-size", "", "Abort when a single file exceeds this size (e.g. 1GB)")
+	extractCmd.Flags().Int64Var(&maxFiles, "max-files", 0, "Abort when more files, directories and links are extracted")
+	extractCmd.Flags().IntVar(&maxDepth, "max-depth", 0, "Abort when a path has more components")
 
 	_ = extractCmd.MarkFlagRequired("output")
 
@@ -32,6 +67,43 @@ all files, directories, and links to the target directory on the local filesyste
 This command copies the entire filesystem from the image to your local machine,
 preserving the directory structure, file permissions, and symbolic links.
 
+Only permission bits are applied by default. --preserve restores more metadata
+when permitted: owner (uid/gid, setuid and setgid bits), time (modification
+times) and xattrs (extended attributes, including file capabilities such as
+security.capability). Restoring owners and capabilities usually requires root,
+otherwise the extraction continues and the failures are reported as warnings.
+
+--map-uid and --map-gid change the restored owners, e.g. '--map-uid 0:1000'
+maps root to uid 1000 and '--map-uid 1000' gives every file to uid 1000, so
+rootless users get predictable ownership.
+
+Paths are resolved inside the output directory: symlinks extracted before are
+followed as if the output were the filesystem root, so an image with 'a -> /etc'
+followed by 'a/passwd' writes output/etc/passwd, never /etc/passwd. Entries
+leaving the output are skipped. With --strict they fail the extraction, as do
+symlinks with absolute targets or targets leaving the output directory.
+
+--include and --exclude extract a part of the image. They take paths, file names
+or globs ('**' crosses directories); an entry is extracted when it matches an
+include, or there are none, and no exclude. The parent directories of extracted
+entries are created with their modes from the image, and hardlinks to files left
+out get a copy of their content.
+
+With --layer a single layer (by digest or by index, where -1 is the top layer)
+is extracted instead of the merged image: files deleted by later layers are
+recovered, and the output shows what that build step produced. Whiteouts of
+the layer are not applied.
+
+Device nodes and FIFOs are skipped by default. With --devices they are created
+with mknod and mkfifo, which is what chroot and VM root filesystems need. Device
+nodes usually require root: without privileges they are recorded in a shell
+script (--devices-manifest) that a later privileged step runs to recreate them.
+
+--max-total-size, --max-file-size, --max-files and --max-depth protect shared
+machines from decompression bombs in third-party images. The limits are checked
+before every entry is written; the extraction aborts with an error and removes
+everything it created.
+
 To export the image as a tar archive instead, use the 'export' command.`,
 	Example: `  # Extract all files from nginx image to a directory
   artship extract nginx:latest -o ./extracted
@@ -39,10 +111,37 @@ To export the image as a tar archive instead, use the 'export' command.`,
   # Extract all files to a specific directory
   artship extract alpine:latest -o ./extracted-alpine
 
+  # Extract a root filesystem with owners, times and file capabilities
+  sudo artship extract debian:12 -o ./rootfs --preserve all
+
+  # Extract as a rootless user, owning every file
+  artship extract debian:12 -o ./rootfs --preserve time,xattrs --map-uid $(id -u) --map-gid $(id -g)
+
+  # Extract an untrusted image, refusing links outside the output
+  artship extract third-party/app:latest -o ./extracted --strict
+
+  # Extract only the time zone database, without compiled Python files
+  artship extract debian:12 -o ./tz --include 'usr/share/zoneinfo/**' --exclude '**/*.pyc'
+
+  # Extract what the top layer adds to the image
+  artship extract myapp:latest -o ./top --layer -1
+
+  # Build a root filesystem for a chroot, recreating device nodes later as root
+  artship extract debian:12 -o ./rootfs --devices
+  sudo sh ./rootfs.devices.sh ./rootfs
+
+  # Extract a third-party image on a shared CI runner
+  artship extract third-party/app:latest -o ./extracted --max-total-size 5GB --max-files 200000
+
   # Extract from a private registry
   artship extract my-registry.com/myapp:v1.0 -o ./extracted-app`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := parseExtractOptions()
+		if err != nil {
+			return err
+		}
+
 		logger := logs.New(verbose)
 		cli := client.New(&client.Options{
 			Username: username,
@@ -53,10 +152,92 @@ To export the image as a tar archive instead, use the 'export' command.`,
 			Logger:   logger,
 		})
 
-		if err := cli.Extract(cmd.Context(), args[0], output); err != nil {
+		if err := cli.Extract(cmd.Context(), args[0], layer, output, opts); err != nil {
 			return fmt.Errorf("failed to extract image: %w", err)
 		}
 
 		return nil
 	},
 }
+
+// parseExtractOptions builds the extraction options from the flags
+func parseExtractOptions() (*tools.ExtractOptions, error) {
+	p, err := tools.ParsePreserve(preserve)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --preserve: %w", err)
+	}
+
+	uids, err := tools.ParseIDMap(mapUID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --map-uid: %w", err)
+	}
+
+	gids, err := tools.ParseIDMap(mapGID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --map-gid: %w", err)
+	}
+
+	if uids != nil || gids != nil {
+		p.Owner = true
+	}
+
+	manifest := devicesManifest
+	if devices && manifest == "" {
+		manifest = filepath.Clean(output) + ".devices.sh"
+	}
+
+	limits, err := parseLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	includes, err := tools.NewMatchers(include, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --include: %w", err)
+	}
+
+	excludes, err := tools.NewMatchers(exclude, false)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --exclude: %w", err)
+	}
+
+	return &tools.ExtractOptions{
+		Include:  includes,
+		Exclude:  excludes,
+		Preserve: p,
+		UIDMap:   uids,
+		GIDMap:   gids,
+		Limits:   limits,
+		Strict:   strict,
+
+		Devices:         devices,
+		DevicesManifest: manifest,
+	}, nil
+}
+
+// parseLimits builds the extraction limits from the flags shared by extract and cp
+func parseLimits() (*tools.Limits, error) {
+	limits := &tools.Limits{
+		MaxFiles: maxFiles,
+		MaxDepth: maxDepth,
+	}
+
+	if maxFiles < 0 || maxDepth < 0 {
+		return nil, fmt.Errorf("--max-files and --max-depth must not be negative")
+	}
+
+	var err error
+	if maxTotalSize != "" {
+		if limits.MaxTotalSize, err = tools.ParseSize(maxTotalSize); err != nil {
+			return nil, fmt.Errorf("invalid --max-total-size: %w", err)
+		}
+	}
+
+	if maxFileSize != "" {
+		if limits.MaxFileSize, err = tools.ParseSize(maxFileSize); err != nil {
+			return nil, fmt.Errorf("invalid --max-file-size: %w", err)
+		}
+	}
+
+	return limits, nil
+}
@@ -17,6 +17,8 @@ func init() {
 	hasCmd.Flags().StringVarP(&auth, "auth", "", "", "Auth for registry authentication")
 	hasCmd.Flags().BoolVarP(&insecure, "insecure", "k", false, "Allow insecure registry connections")
 	hasCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose debug output")
+	hasCmd.Flags().StringVarP(&layer, "layer", "l", "", "Search a single layer: digest or index, negative from the top layer")
+	hasCmd.Flags().BoolVar(&regex, "regex", false, "Treat the artifact as a regular expression matched against image paths")
 
 	rootCmd.AddCommand(hasCmd)
 }
@@ -26,13 +28,25 @@ var hasCmd = &cobra.Command{
 	Short: "Check if an artifact exists in an OCI/Docker image",
 	Long: `Has checks whether a specific artifact exists in an OCI/Docker image
 without downloading or extracting it. This is useful for quickly verifying
-the presence of files or directories before performing operations.`,
+the presence of files or directories before performing operations.
+
+The artifact can be a path, a file name, a glob pattern or, with --regex,
+a regular expression.
+
+With --layer only a single layer (by digest or by index, where -1 is the top
+layer) is searched, e.g. to check which build step added a file.`,
 	Example: `  # Check if nginx binary exists
   artship has nginx:latest nginx
   
   # Check for configuration file
   artship has nginx:latest /etc/nginx/nginx.conf
   
+  # Check if any shared library is present
+  artship has debian:12 'usr/lib/**/*.so*'
+
+  # Check if the top layer adds any Python bytecode
+  artship has myapp:latest '**/*.pyc' --layer -1
+
   # Check with authentication
   artship has private-registry.com/app:latest myapp -u user -p pass`,
 	Args: cobra.ExactArgs(2),
@@ -48,7 +62,7 @@ the presence of files or directories before performing operations.`,
 			Logger:   logger,
 		})
 
-		if err := cli.Has(cmd.Context(), args[0], args[1]); err != nil {
+		if err := cli.Has(cmd.Context(), args[0], layer, args[1], regex); err != nil {
 			if !errors.Is(err, client.ErrNotFound) {
 				return fmt.Errorf("failed to check artifact: %w", err)
 			}
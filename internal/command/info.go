@@ -17,6 +17,8 @@ func init() {
 	infoCmd.Flags().StringVarP(&auth, "auth", "", "", "Auth for registry authentication")
 	infoCmd.Flags().BoolVarP(&insecure, "insecure", "k", false, "Allow insecure registry connections")
 	infoCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose debug output")
+	infoCmd.Flags().StringVarP(&layer, "layer", "l", "", "Search a single layer: digest or index, negative from the top layer")
+	infoCmd.Flags().BoolVar(&regex, "regex", false, "Treat the artifact as a regular expression matched against image paths")
 
 	rootCmd.AddCommand(infoCmd)
 }
@@ -26,13 +28,25 @@ var infoCmd = &cobra.Command{
 	Short: "Show detailed information about an artifact from an OCI/Docker image",
 	Long: `Info displays detailed metadata information about a specific artifact in an OCI/Docker image,
 including type, size, permissions, and link targets. This is useful for understanding
-artifact properties before extracting them.`,
+artifact properties before extracting them.
+
+The artifact can be a path, a file name, a glob pattern or, with --regex,
+a regular expression. Patterns show every matching artifact.
+
+With --layer only a single layer (by digest or by index, where -1 is the top
+layer) is searched, showing the artifact as that build step produced it.`,
 	Example: `  # Show detailed info about nginx binary
   artship info nginx:latest nginx
   
   # Show info about configuration file
   artship info nginx:latest /etc/nginx/nginx.conf
   
+  # Show info about all matching libraries
+  artship info debian:12 'usr/lib/**/libssl*'
+
+  # Show the binary as the top layer wrote it
+  artship info myapp:latest app/bin/server --layer -1
+
   # Show info with authentication
   artship info private-registry.com/app:latest myapp -u user -p pass`,
 	Args: cobra.ExactArgs(2),
@@ -49,24 +63,26 @@ artifact properties before extracting them.`,
 		})
 
 		// Get detailed artifact information
-		info, err := cli.GetArtifact(cmd.Context(), args[0], args[1])
+		infos, err := cli.GetArtifacts(cmd.Context(), args[0], layer, args[1], regex)
 		if err != nil {
 			return fmt.Errorf("failed to get artifact info: %w", err)
 		}
 
 		// Print artifact info with colors
-		logger.Info("")
-		logger.Info(logs.BoldBlue("Artifact Information"))
-		logger.Info(logs.Gray("─────────────────────────────────────────────────────────────"))
-		logger.Info("Path: %s", logs.Blue(info.Path))
-		logger.Info("Type: %s", logs.Yellow(info.Type))
+		for _, info := range infos {
+			logger.Info("")
+			logger.Info(logs.BoldBlue("Artifact Information"))
+			logger.Info(logs.Gray("─────────────────────────────────────────────────────────────"))
+			logger.Info("Path: %s", logs.Blue(info.Path))
+			logger.Info("Type: %s", logs.Yellow(info.Type))
 
-		sizeStr := tools.FormatSize(info.Size)
-		if info.Type == "dir" || info.Type == "symlink" || info.Type == "hardlink" {
-			sizeStr = "-"
+			sizeStr := tools.FormatSize(info.Size)
+			if info.Type == "dir" || info.Type == "symlink" || info.Type == "hardlink" {
+				sizeStr = "-"
+			}
+			logger.Info("Size: %s", logs.Green(sizeStr))
+			logger.Info("Mode: %s", logs.Gray(info.Mode))
 		}
-		logger.Info("Size: %s", logs.Green(sizeStr))
-		logger.Info("Mode: %s", logs.Gray(info.Mode))
 
 		return nil
 	},
@@ -0,0 +1,81 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ipaqsa/artship/internal/client"
+	"github.com/ipaqsa/artship/internal/logs"
+)
+
+func init() {
+	layersCmd.Flags().StringVarP(&username, "username", "u", "", "Username for registry authentication")
+	layersCmd.Flags().StringVarP(&password, "password", "p", "", "Password for registry authentication")
+	layersCmd.Flags().StringVarP(&token, "token", "t", "", "Token for registry authentication")
+	layersCmd.Flags().StringVarP(&auth, "auth", "", "", "Auth for registry authentication")
+	layersCmd.Flags().BoolVarP(&insecure, "insecure", "k", false, "Allow insecure registry connections")
+	layersCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose debug output")
+	layersCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Output format (json)")
+
+	rootCmd.AddCommand(layersCmd)
+}
+
+var layersCmd = &cobra.Command{
+	Use:   "layers <image> [layer]",
+	Short: "Show what each layer of an OCI/Docker image added, modified and deleted",
+	Long: `Layers lists each layer of an image with the command that created it
+(from the image config history), its size and the number of files it added,
+modified and deleted relative to the layers below it. Whiteout files and
+opaque directories are interpreted as deletions.
+
+Pass a layer digest or index (negative indexes count from the top layer)
+to show the changed files of a single layer. Separate negative indexes
+from the flags with '--'.`,
+	Example: `  # List the layer history
+  artship layers nginx:latest
+
+  # Show the files changed by the top layer
+  artship layers nginx:latest -- -1
+
+  # Show a layer by digest as JSON
+  artship layers nginx:latest sha256:abc123... -o json`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var layerSpec string
+		if len(args) > 1 {
+			layerSpec = args[1]
+		}
+
+		logger := logs.New(verbose)
+
+		cli := client.New(&client.Options{
+			Username: username,
+			Password: password,
+			Token:    token,
+			Auth:     auth,
+			Insecure: insecure,
+			Logger:   logger,
+		})
+
+		history, err := cli.Layers(cmd.Context(), args[0], layerSpec)
+		if err != nil {
+			return fmt.Errorf("failed to get layer history: %w", err)
+		}
+
+		switch outputFormat {
+		case "json":
+			jsonStr, err := history.ToJSON()
+			if err != nil {
+				return fmt.Errorf("failed to generate JSON output: %w", err)
+			}
+			fmt.Println(jsonStr)
+		case "":
+			fmt.Print(history.String())
+		default:
+			return fmt.Errorf("unsupported output format '%s'", outputFormat)
+		}
+
+		return nil
+	},
+}
@@ -20,20 +20,24 @@ func init() {
 	listCmd.Flags().StringVarP(&password, "password", "p", "", "Password for registry authentication")
 	listCmd.Flags().StringVarP(&token, "token", "t", "", "Token for registry authentication")
 	listCmd.Flags().StringVarP(&auth, "auth", "", "", "Auth for registry authentication")
-	listCmd.Flags().StringVarP(&filter, "filter", "f", "", "Filter by type: file, dir, symlink, hardlink, all")
+	listCmd.Flags().StringVarP(&filter, "filter", "f", "", "Filter by type: file, dir, symlink, hardlink, all (with --layer also deleted, opaque)")
 	listCmd.Flags().BoolVarP(&detailed, "detailed", "d", false, "Show detailed info (size, type, permissions)")
-	listCmd.Flags().StringVarP(&layer, "layer", "l", "", "Show files from specific layer (layer digest)")
+	listCmd.Flags().StringVarP(&layer, "layer", "l", "", "Show files from specific layer: digest or index, negative from the top layer")
 	listCmd.Flags().BoolVarP(&insecure, "insecure", "k", false, "Allow insecure registry connections")
 	listCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose debug output")
+	listCmd.Flags().BoolVar(&regex, "regex", false, "Treat the pattern as a regular expression matched against image paths")
 
 	rootCmd.AddCommand(listCmd)
 }
 
 var listCmd = &cobra.Command{
-	Use:   "ls <image>",
+	Use:   "ls <image> [pattern]",
 	Short: "Show available artifacts in an OCI/Docker image",
 	Long: `Show all files and directories available in an OCI/Docker image.
-This command downloads the image layers and lists all available artifacts`,
+This command downloads the image layers and lists all available artifacts.
+
+An optional pattern limits the listing to a path, a file name, a directory,
+a glob pattern ('usr/lib/**/*.so*') or, with --regex, a regular expression.`,
 	Example: `  # List all artifacts in an image
   artship ls nginx:latest
 
@@ -43,12 +47,18 @@ This command downloads the image layers and lists all available artifacts`,
   # List only files
   artship ls nginx:latest --filter file
 
-  # List files from specific layer
+  # List shared libraries
+  artship ls debian:12 'usr/lib/**/*.so*'
+
+  # List files from specific layer, including the files it deletes
   artship ls nginx:latest --layer sha256:abc123...
 
+  # List files added by the top layer
+  artship ls nginx:latest --layer -1
+
   # List directories with info
   artship ls nginx:latest -f dir -d`,
-	Args: cobra.ExactArgs(1),
+	Args: cobra.RangeArgs(1, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		logger := logs.New(verbose)
 
@@ -61,7 +71,12 @@ This command downloads the image layers and lists all available artifacts`,
 			Logger:   logger,
 		})
 
-		artifacts, err := cli.List(cmd.Context(), args[0], filter, layer)
+		var pattern string
+		if len(args) > 1 {
+			pattern = args[1]
+		}
+
+		artifacts, err := cli.List(cmd.Context(), args[0], filter, layer, pattern, regex)
 		if err != nil {
 			return fmt.Errorf("failed to list artifacts: %w", err)
 		}
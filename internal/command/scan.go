@@ -0,0 +1,109 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ipaqsa/artship/internal/client"
+	"github.com/ipaqsa/artship/internal/logs"
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+var (
+	osvDB  string
+	failOn string
+)
+
+func init() {
+	scanCmd.Flags().StringVarP(&username, "username", "u", "", "Username for registry authentication")
+	scanCmd.Flags().StringVarP(&password, "password", "p", "", "Password for registry authentication")
+	scanCmd.Flags().StringVarP(&token, "token", "t", "", "Token for registry authentication")
+	scanCmd.Flags().StringVarP(&auth, "auth", "", "", "Auth for registry authentication")
+	scanCmd.Flags().BoolVarP(&insecure, "insecure", "k", false, "Allow insecure registry connections")
+	scanCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose debug output")
+	scanCmd.Flags().StringVar(&osvDB, "db", "", "Path to the local OSV database (JSON files or zip archives, required)")
+	scanCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Output format (json, sarif)")
+	scanCmd.Flags().StringVar(&failOn, "fail-on", "", "Exit with an error if a vulnerability with this or higher severity is found (low, medium, high, critical)")
+
+	_ = scanCmd.MarkFlagRequired("db")
+
+	rootCmd.AddCommand(scanCmd)
+}
+
+var scanCmd = &cobra.Command{
+	Use:   "scan <image>",
+	Short: "Scan an OCI/Docker image for known vulnerabilities using a local OSV database",
+	Long: `Scan inventories the packages installed in an OCI/Docker image and matches
+them against a local copy of the OSV database, without network access to any
+vulnerability service.
+
+The inventory includes:
+- Debian/Ubuntu packages (dpkg status database)
+- Alpine packages (apk installed database)
+- Go standard library and modules embedded in Go binaries
+
+The database directory may contain OSV JSON files and the ecosystem zip
+archives published at https://osv.dev (e.g. Debian/all.zip, Go/all.zip).
+
+Use --fail-on to exit with an error when vulnerabilities at or above the
+given severity are found.`,
+	Example: `  # Scan an image using a synced OSV dump
+  artship scan nginx:latest --db ./osv
+
+  # Fail a CI job on high and critical vulnerabilities
+  artship scan myapp:latest --db ./osv --fail-on high
+
+  # Produce a SARIF report for code scanning
+  artship scan myapp:latest --db ./osv -o sarif > artship.sarif`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if failOn != "" && tools.SeverityRank(failOn) == 0 {
+			return fmt.Errorf("invalid severity threshold '%s' (use low, medium, high or critical)", failOn)
+		}
+
+		logger := logs.New(verbose)
+
+		cli := client.New(&client.Options{
+			Username: username,
+			Password: password,
+			Token:    token,
+			Auth:     auth,
+			Insecure: insecure,
+			Logger:   logger,
+		})
+
+		result, err := cli.Scan(cmd.Context(), args[0], osvDB)
+		if err != nil {
+			return fmt.Errorf("failed to scan image: %w", err)
+		}
+
+		switch outputFormat {
+		case "json":
+			jsonStr, err := result.ToJSON()
+			if err != nil {
+				return fmt.Errorf("failed to generate JSON output: %w", err)
+			}
+			fmt.Println(jsonStr)
+		case "sarif":
+			sarif, err := result.ToSARIF()
+			if err != nil {
+				return fmt.Errorf("failed to generate SARIF output: %w", err)
+			}
+			fmt.Println(sarif)
+		case "":
+			fmt.Print(result.String())
+		default:
+			return fmt.Errorf("unsupported output format '%s'", outputFormat)
+		}
+
+		if failOn != "" {
+			if count := result.CountAtLeast(failOn); count > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("found %d vulnerabilities with severity %s or higher", count, failOn)
+			}
+		}
+
+		return nil
+	},
+}
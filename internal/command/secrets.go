@@ -0,0 +1,106 @@
+package command
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ipaqsa/artship/internal/client"
+	"github.com/ipaqsa/artship/internal/logs"
+	"github.com/ipaqsa/artship/internal/tools"
+)
+
+var (
+	secretRules       string
+	secretMaxFileSize string
+)
+
+func init() {
+	secretsCmd.Flags().StringVarP(&username, "username", "u", "", "Username for registry authentication")
+	secretsCmd.Flags().StringVarP(&password, "password", "p", "", "Password for registry authentication")
+	secretsCmd.Flags().StringVarP(&token, "token", "t", "", "Token for registry authentication")
+	secretsCmd.Flags().StringVarP(&auth, "auth", "", "", "Auth for registry authentication")
+	secretsCmd.Flags().BoolVarP(&insecure, "insecure", "k", false, "Allow insecure registry connections")
+	secretsCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose debug output")
+	secretsCmd.Flags().StringVar(&secretRules, "rules", "", "Path to a YAML rule file extending or overriding the default rules")
+	secretsCmd.Flags().StringVar(&secretMaxFileSize, "max-file-size", "10MB", "Skip files larger than this size")
+	secretsCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Output format (json)")
+
+	rootCmd.AddCommand(secretsCmd)
+}
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets <image>",
+	Short: "Scan every layer of an OCI/Docker image for leaked credentials",
+	Long: `Secrets scans each layer of an image separately, so credentials that were
+added in one layer and deleted in a later one are still reported: they remain
+in the image and can be downloaded by anyone who can pull it.
+
+Default rules detect private keys, cloud provider and SaaS tokens, npm and
+Docker registry credentials and high-entropy secret assignments.
+
+Custom rules are read from a YAML file:
+
+  rules:
+    - id: internal-token
+      description: Internal API token
+      regex: 'itk_(?P<secret>[a-z0-9]{32})'
+      paths: ["etc/**"]
+      entropy: 3.5
+  disable: [generic-secret]
+  allowPaths: ["usr/share/doc/**"]
+
+A rule with the ID of a default rule replaces it. The 'secret' named group
+is reported and checked against the entropy threshold.`,
+	Example: `  # Scan an image with the default rules
+  artship secrets myapp:latest
+
+  # Use a custom rule file and produce JSON
+  artship secrets myapp:latest --rules secrets.yaml -o json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		maxFileSize, err := tools.ParseSize(secretMaxFileSize)
+		if err != nil {
+			return fmt.Errorf("invalid max file size: %w", err)
+		}
+
+		logger := logs.New(verbose)
+
+		cli := client.New(&client.Options{
+			Username: username,
+			Password: password,
+			Token:    token,
+			Auth:     auth,
+			Insecure: insecure,
+			Logger:   logger,
+		})
+
+		result, err := cli.Secrets(cmd.Context(), args[0], &client.SecretsOptions{
+			RulesFile:   secretRules,
+			MaxFileSize: maxFileSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to scan image for secrets: %w", err)
+		}
+
+		switch outputFormat {
+		case "json":
+			jsonStr, err := result.ToJSON()
+			if err != nil {
+				return fmt.Errorf("failed to generate JSON output: %w", err)
+			}
+			fmt.Println(jsonStr)
+		case "":
+			fmt.Print(result.String())
+		default:
+			return fmt.Errorf("unsupported output format '%s'", outputFormat)
+		}
+
+		if len(result.Findings) > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("found %d secrets", len(result.Findings))
+		}
+
+		return nil
+	},
+}
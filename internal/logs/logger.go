@@ -36,3 +36,8 @@ func (l *Logger) Debug(msg string, args ...any) {
 func (l *Logger) Warn(msg string, args ...any) {
 	_, _ = fmt.Fprintf(l.output, "[WARN] %s\n", fmt.Sprintf(msg, args...))
 }
+
+// SetOutput changes where messages are written, e.g. to stderr when stdout carries data
+func (l *Logger) SetOutput(w io.Writer) {
+	l.output = w
+}
@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"bytes"
+)
+
+var (
+	elfMagic = []byte("\x7fELF")
+	peMagic  = []byte("MZ")
+
+	machoMagics = [][]byte{
+		{0xfe, 0xed, 0xfa, 0xce}, // 32-bit big endian
+		{0xfe, 0xed, 0xfa, 0xcf}, // 64-bit big endian
+		{0xce, 0xfa, 0xed, 0xfe}, // 32-bit little endian
+		{0xcf, 0xfa, 0xed, 0xfe}, // 64-bit little endian
+		{0xca, 0xfe, 0xba, 0xbe}, // universal binary
+	}
+)
+
+// IsELF checks if the leading bytes of a file belong to an ELF object
+func IsELF(magic []byte) bool {
+	return bytes.HasPrefix(magic, elfMagic)
+}
+
+// IsExecutable checks if the leading bytes of a file belong to a known executable format (ELF, Mach-O, PE)
+func IsExecutable(magic []byte) bool {
+	if IsELF(magic) || bytes.HasPrefix(magic, peMagic) {
+		return true
+	}
+
+	for _, m := range machoMagics {
+		if bytes.HasPrefix(magic, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsBinary checks if the content looks binary, using the same heuristic as git:
+// a NUL byte within the first 8000 bytes
+func IsBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0
+}
@@ -0,0 +1,166 @@
+package tools
+
+import (
+	"archive/tar"
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrChecksumMismatch is returned when content does not match the expected SHA256
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ParseChecksum validates a hex encoded SHA256 and returns it in lower case
+func ParseChecksum(s string) (string, error) {
+	sum := strings.ToLower(strings.TrimSpace(s))
+	if len(sum) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid SHA256 '%s': expected %d hex characters", s, sha256.Size*2)
+	}
+
+	if _, err := hex.DecodeString(sum); err != nil {
+		return "", fmt.Errorf("invalid SHA256 '%s': %w", s, err)
+	}
+
+	return sum, nil
+}
+
+// ParseChecksums reads a SHA256SUMS file ('<hex>  <path>' lines, as written by sha256sum)
+// and returns the checksums by cleaned path
+func ParseChecksums(r io.Reader) (map[string]string, error) {
+	sums := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		value, name, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected '<sha256>  <path>'", line)
+		}
+
+		sum, err := ParseChecksum(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		// A '*' marks binary mode in sha256sum output
+		name = strings.TrimPrefix(strings.TrimLeft(name, " "), "*")
+		sums[CleanPath(name)] = sum
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read checksums: %w", err)
+	}
+
+	return sums, nil
+}
+
+// WriteChecksums writes checksums in the sha256sum format, sorted by path
+func WriteChecksums(w io.Writer, sums map[string]string) error {
+	names := make([]string, 0, len(sums))
+	for name := range sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, err := fmt.Fprintf(w, "%s  %s\n", sums[name], name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CopyFileChecked writes a regular file entry to the target computing its SHA256 on the way.
+// With an expected checksum the content goes to a temporary file first and is moved
+// to the target only if it matches, so a mismatching file never appears there
+func CopyFileChecked(r io.Reader, header *tar.Header, target, expected string) (string, error) {
+	h := sha256.New()
+	if expected == "" {
+		if err := extractFile(io.TeeReader(r, h), header, target); err != nil {
+			return "", err
+		}
+
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	targetDir := filepath.Dir(target)
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return "", fmt.Errorf("create the target dir '%s': %w", targetDir, err)
+	}
+
+	tmp, err := os.CreateTemp(targetDir, ".artship-*")
+	if err != nil {
+		return "", fmt.Errorf("create a temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err = writeChecked(tmp, r, h, expected); err != nil {
+		tmp.Close()
+		return "", err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return "", fmt.Errorf("write '%s': %w", target, err)
+	}
+
+	if err = os.Chmod(tmp.Name(), os.FileMode(header.Mode).Perm()); err != nil {
+		return "", fmt.Errorf("set mode of '%s': %w", target, err)
+	}
+
+	if err = os.Rename(tmp.Name(), target); err != nil {
+		return "", fmt.Errorf("move the file to '%s': %w", target, err)
+	}
+
+	return expected, nil
+}
+
+// SpoolChecked copies the content to a temporary file and verifies its checksum,
+// so it can be passed on only if it matches. The caller closes and removes the file
+func SpoolChecked(r io.Reader, expected string) (*os.File, error) {
+	tmp, err := os.CreateTemp("", "artship-*")
+	if err != nil {
+		return nil, fmt.Errorf("create a temporary file: %w", err)
+	}
+
+	if err = writeChecked(tmp, r, sha256.New(), expected); err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	return tmp, nil
+}
+
+// VerifyChecksum compares a computed checksum with the expected one, if any
+func VerifyChecksum(sum, expected string) error {
+	if expected != "" && sum != expected {
+		return fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, expected, sum)
+	}
+
+	return nil
+}
+
+// writeChecked copies the content to the writer and compares its checksum with the expected one
+func writeChecked(w io.Writer, r io.Reader, h hash.Hash, expected string) error {
+	if _, err := io.Copy(io.MultiWriter(w, h), r); err != nil {
+		return fmt.Errorf("copy file content: %w", err)
+	}
+
+	return VerifyChecksum(hex.EncodeToString(h.Sum(nil)), expected)
+}
@@ -0,0 +1,174 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestParseChecksums(t *testing.T) {
+	a, b := sha256Hex("a"), sha256Hex("b")
+
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:  "sha256sum output",
+			input: a + "  app/bin/server\n" + b + " *app/bin/client\n",
+			want:  map[string]string{"app/bin/server": a, "app/bin/client": b},
+		},
+		{
+			name:  "paths are cleaned",
+			input: a + "  ./server\n" + b + "  /etc/app/../app.conf\n",
+			want:  map[string]string{"server": a, "etc/app.conf": b},
+		},
+		{
+			name:  "comments, blank lines and upper case",
+			input: "# checksums\n\n" + strings.ToUpper(a) + "  server\n",
+			want:  map[string]string{"server": a},
+		},
+		{
+			name:    "missing path",
+			input:   a + "\n",
+			wantErr: "line 1: expected '<sha256>  <path>'",
+		},
+		{
+			name:    "invalid checksum",
+			input:   "# checksums\n" + a[:10] + "  server\n",
+			wantErr: "line 2: invalid SHA256",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChecksums(strings.NewReader(tt.input))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseChecksums() error = %v, want '%s'", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("ParseChecksums() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteChecksums(t *testing.T) {
+	sums := map[string]string{"b/file": sha256Hex("b"), "a": sha256Hex("a")}
+
+	var buf bytes.Buffer
+	if err := WriteChecksums(&buf, sums); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := sha256Hex("a") + "  a\n" + sha256Hex("b") + "  b/file\n"; buf.String() != want {
+		t.Errorf("WriteChecksums() = %q, want %q", buf.String(), want)
+	}
+
+	parsed, err := ParseChecksums(&buf)
+	if err != nil || !maps.Equal(parsed, sums) {
+		t.Errorf("the written checksums are parsed as %v, %v", parsed, err)
+	}
+}
+
+func TestCopyFileChecked(t *testing.T) {
+	const content = "#!/bin/sh\necho server\n"
+
+	tests := []struct {
+		name     string
+		expected string
+		// existing is the content of the target before the copy
+		existing string
+		wantErr  error
+	}{
+		{name: "without a checksum"},
+		{name: "matching checksum", expected: sha256Hex(content)},
+		{name: "mismatching checksum", expected: sha256Hex("other"), wantErr: ErrChecksumMismatch},
+		{name: "mismatching checksum of an existing file", expected: sha256Hex("other"), existing: "original", wantErr: ErrChecksumMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "bin")
+			target := filepath.Join(dir, "server")
+			if tt.existing != "" {
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(target, []byte(tt.existing), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			header := &tar.Header{Name: "app/bin/server", Typeflag: tar.TypeReg, Mode: 0o755, Size: int64(len(content))}
+			sum, err := CopyFileChecked(strings.NewReader(content), header, target, tt.expected)
+
+			entries, readErr := os.ReadDir(dir)
+			if readErr != nil {
+				t.Fatal(readErr)
+			}
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected error '%v', got %v", tt.wantErr, err)
+				}
+
+				// The content never reaches the target, and the temporary file is removed
+				got, statErr := os.ReadFile(target)
+				switch {
+				case tt.existing == "" && !os.IsNotExist(statErr):
+					t.Errorf("the mismatching file is written to the target: %q, %v", got, statErr)
+				case tt.existing != "" && string(got) != tt.existing:
+					t.Errorf("the existing target is changed: %q, %v", got, statErr)
+				}
+
+				if want := min(len(tt.existing), 1); len(entries) != want {
+					t.Errorf("the target directory contains %d entries, want %d", len(entries), want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if sum != sha256Hex(content) {
+				t.Errorf("CopyFileChecked() = %s, want %s", sum, sha256Hex(content))
+			}
+
+			got, err := os.ReadFile(target)
+			if err != nil || string(got) != content {
+				t.Errorf("the target has content %q, %v", got, err)
+			}
+
+			stat, err := os.Stat(target)
+			if err != nil || stat.Mode().Perm()&0o100 == 0 {
+				t.Errorf("the target is not executable: %v, %v", stat.Mode(), err)
+			}
+
+			if len(entries) != 1 {
+				t.Errorf("the target directory contains %d entries, want only the target", len(entries))
+			}
+		})
+	}
+}
@@ -17,6 +17,16 @@ type CopyResult struct {
 	LinksCreated   int64
 	TotalSize      int64
 	ExecutionTime  time.Duration
+	// DevicesCreated counts device nodes and FIFOs, DevicesRecorded the ones left to the manifest
+	DevicesCreated  int64
+	DevicesRecorded int64
+	// EntriesSkipped counts entries left out by the include and exclude filters
+	EntriesSkipped int64
+	// PendingLinks maps hardlink targets left out by the filters to the links that need
+	// their content, see CopyLinkTargets
+	PendingLinks map[string][]string
+	// Warnings describe problems that did not abort the extraction
+	Warnings []string
 }
 
 // String returns a formatted string representation of the copy result
@@ -36,9 +46,28 @@ func (r CopyResult) Print() {
 	fmt.Print(r.String() + "\n")
 }
 
-func CopyTar(_ context.Context, rc io.ReadCloser, output string) (CopyResult, error) {
+// CopyTar extracts the tar stream into the output directory.
+// If the extraction fails, e.g. on a limit, the paths it created are removed
+func CopyTar(_ context.Context, rc io.ReadCloser, output string, opts *ExtractOptions) (res CopyResult, err error) {
 	startTime := time.Now()
-	res := CopyResult{}
+	if opts == nil {
+		opts = &ExtractOptions{}
+	}
+	metadata := newMetadataRestorer(opts)
+	limiter := NewLimiter(opts.Limits)
+	parents := newParentDirs(output)
+	var manifest DeviceManifest
+
+	var created CreatedPaths
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		if cleanupErr := created.Remove(); cleanupErr != nil {
+			err = fmt.Errorf("%w; remove the partial output: %v", err, cleanupErr)
+		}
+	}()
 
 	reader := tar.NewReader(rc)
 	for {
@@ -50,19 +79,66 @@ func CopyTar(_ context.Context, rc io.ReadCloser, output string) (CopyResult, er
 			return res, fmt.Errorf("read tar header: %w", err)
 		}
 
-		// Skip whiteout files (Docker layer deletion markers)
-		if strings.Contains(header.Name, ".wh.") {
+		// Skip whiteout and opaque directory markers of a raw layer
+		if IsWhiteout(header.Name) {
 			continue
 		}
 
-		target := filepath.Join(output, header.Name)
+		// The root entry './' is the output directory itself
+		if CleanPath(header.Name) == "" {
+			continue
+		}
 
 		// Ensure the path is within our output directory (security check)
-		if !strings.HasPrefix(target, filepath.Clean(output)+string(os.PathSeparator)) {
+		if !isWithin(output, filepath.Join(output, header.Name)) {
+			if opts.Strict {
+				return res, fmt.Errorf("path '%s' is outside the target directory: %w", header.Name, ErrUnsafePath)
+			}
+
 			fmt.Printf("Warning: Skipping path outside target directory: '%s'\n", header.Name)
 			continue
 		}
 
+		// Parent directories are resolved on disk, so links extracted before never lead outside
+		target, err := SecureJoin(output, header.Name)
+		if err != nil {
+			return res, err
+		}
+
+		name := CleanPath(header.Name)
+		if header.Typeflag == tar.TypeDir {
+			parents.seen(name, header)
+		}
+
+		if !opts.selected(name) {
+			// A parent created for selected entries before its own entry gets the image mode now
+			if parents.adopt(name) {
+				if err = os.Chmod(target, fileMode(header)); err != nil {
+					return res, fmt.Errorf("set mode of '%s': %w", target, err)
+				}
+				metadata.restore(header, target)
+				res.DirsCreated++
+			}
+
+			res.EntriesSkipped++
+			continue
+		}
+
+		if err = limiter.Check(header); err != nil {
+			return res, err
+		}
+		created.Track(target)
+
+		// Only selected subtrees are extracted, their parents are created with image modes
+		if opts.filtered() {
+			dirs, err := parents.ensure(name, metadata, &created)
+			if err != nil {
+				return res, err
+			}
+
+			res.DirsCreated += dirs
+		}
+
 		switch header.Typeflag {
 		case tar.TypeReg:
 			if err = extractFile(reader, header, target); err != nil {
@@ -77,39 +153,305 @@ func CopyTar(_ context.Context, rc io.ReadCloser, output string) (CopyResult, er
 				return res, fmt.Errorf("extract the directory '%s': %w", header.Name, err)
 			}
 
+			// The directory may exist already, e.g. as a parent of an entry from an upper layer
+			if err = os.Chmod(target, fileMode(header)); err != nil {
+				return res, fmt.Errorf("set mode of '%s': %w", target, err)
+			}
+
+			parents.made[name] = true
 			res.DirsCreated++
 
-		case tar.TypeSymlink, tar.TypeLink:
+		case tar.TypeSymlink:
+			if opts.Strict {
+				if err = CheckLink(header); err != nil {
+					return res, err
+				}
+			}
+
 			if err = extractLink(header, target); err != nil {
 				return res, fmt.Errorf("extract the link '%s': %w", header.Name, err)
 			}
 
 			res.LinksCreated++
 
+		case tar.TypeLink:
+			if opts.Strict {
+				if err = CheckLink(header); err != nil {
+					return res, err
+				}
+			}
+
+			// Hardlink names are relative to the archive root, not to the link
+			if !isWithin(output, filepath.Join(output, header.Linkname)) {
+				if opts.Strict {
+					return res, fmt.Errorf("hardlink '%s' -> '%s' leaves the target directory: %w", header.Name, header.Linkname, ErrUnsafePath)
+				}
+
+				fmt.Printf("Warning: Skipping hardlink to a path outside target directory: '%s' -> '%s'\n", header.Name, header.Linkname)
+				continue
+			}
+
+			source, err := SecureJoin(output, header.Linkname)
+			if err != nil {
+				return res, err
+			}
+
+			// The target is left out by the filters, the content is copied to the link later
+			if link := CleanPath(header.Linkname); !opts.selected(link) {
+				if res.PendingLinks == nil {
+					res.PendingLinks = make(map[string][]string)
+				}
+
+				res.PendingLinks[link] = append(res.PendingLinks[link], target)
+				res.LinksCreated++
+				continue
+			}
+
+			if err = LinkOrCopy(source, target); err != nil {
+				return res, fmt.Errorf("extract the hardlink '%s' -> '%s': %w", header.Name, header.Linkname, err)
+			}
+
+			res.LinksCreated++
+
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			if !opts.Devices {
+				fmt.Printf("Warning: Skipping special file '%s'\n", header.Name)
+				continue
+			}
+
+			if err = extractSpecialFile(header, target); err != nil {
+				if !needsPrivileges(err) {
+					return res, fmt.Errorf("extract the special file '%s': %w", header.Name, err)
+				}
+
+				// The script gets the resolved path, so symlinked parents of the image never lead outside
+				rel, err := filepath.Rel(output, target)
+				if err != nil {
+					return res, fmt.Errorf("record the special file '%s': %w", header.Name, err)
+				}
+
+				manifest.Add(header, filepath.ToSlash(rel))
+				res.DevicesRecorded++
+				continue
+			}
+
+			res.DevicesCreated++
+
 		default:
-			// Other types (character devices, block devices, FIFOs, etc.)
+			// Other types (sparse files, GNU extensions, etc.)
 			fmt.Printf("Warning: Skipping unsupported file type %d for '%s'\n", header.Typeflag, header.Name)
+			continue
 		}
+
+		metadata.restore(header, target)
 	}
 
+	res.Warnings = metadata.finish()
+
+	if manifest.Len() > 0 {
+		if opts.DevicesManifest == "" {
+			res.Warnings = append(res.Warnings, fmt.Sprintf("%d special files need privileges and are not created", manifest.Len()))
+		} else if err = manifest.WriteFile(opts.DevicesManifest); err != nil {
+			return res, err
+		}
+	}
 	res.ExecutionTime = time.Since(startTime)
 
 	return res, nil
 }
 
+// CopyLinkTargets writes the content of hardlink targets that were not extracted to their links,
+// read from a new stream of the same image. The first link gets the content, the others are linked to it
+func CopyLinkTargets(rc io.ReadCloser, links map[string][]string) error {
+	err := WalkTar(rc, func(r io.Reader, header *tar.Header) error {
+		name := CleanPath(header.Name)
+		targets, ok := links[name]
+		if !ok || header.Typeflag != tar.TypeReg {
+			return nil
+		}
+		delete(links, name)
+
+		if err := extractFile(r, header, targets[0]); err != nil {
+			return fmt.Errorf("extract the hardlink target '%s': %w", name, err)
+		}
+
+		for _, target := range targets[1:] {
+			if err := LinkOrCopy(targets[0], target); err != nil {
+				return fmt.Errorf("extract the hardlink '%s': %w", target, err)
+			}
+		}
+
+		if len(links) == 0 {
+			return ErrStopWalk
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for name := range links {
+		return fmt.Errorf("hardlink target '%s' is not found in the image", name)
+	}
+
+	return nil
+}
+
+// parentDirs creates the parent directories of selected entries with the modes of their
+// image entries, also when a directory is filtered out or comes after its contents
+type parentDirs struct {
+	output   string
+	headers  map[string]*tar.Header
+	made     map[string]bool
+	implicit map[string]bool
+}
+
+func newParentDirs(output string) *parentDirs {
+	return &parentDirs{
+		output:   output,
+		headers:  make(map[string]*tar.Header),
+		made:     make(map[string]bool),
+		implicit: make(map[string]bool),
+	}
+}
+
+// seen records the directory entry of the image
+func (d *parentDirs) seen(name string, header *tar.Header) {
+	d.headers[name] = header
+}
+
+// adopt checks if the directory was created as a parent before its entry was seen
+func (d *parentDirs) adopt(name string) bool {
+	if !d.implicit[name] {
+		return false
+	}
+
+	delete(d.implicit, name)
+	return true
+}
+
+// ensure creates the missing parents of the entry and returns the number of directories
+// created from image entries. Parents without an entry yet get the default mode until it comes
+func (d *parentDirs) ensure(name string, metadata *metadataRestorer, created *CreatedPaths) (int64, error) {
+	var count int64
+
+	parts := strings.Split(name, "/")
+	for i := 1; i < len(parts); i++ {
+		parent := strings.Join(parts[:i], "/")
+		if d.made[parent] {
+			continue
+		}
+		d.made[parent] = true
+
+		target, err := SecureJoin(d.output, parent)
+		if err != nil {
+			return count, err
+		}
+		created.Track(target)
+
+		header, ok := d.headers[parent]
+		if !ok {
+			if err = os.MkdirAll(target, 0755); err != nil {
+				return count, fmt.Errorf("create the parent dir '%s': %w", target, err)
+			}
+
+			d.implicit[parent] = true
+			continue
+		}
+
+		if err = extractDir(header, target); err != nil {
+			return count, fmt.Errorf("extract the directory '%s': %w", parent, err)
+		}
+
+		if err = os.Chmod(target, fileMode(header)); err != nil {
+			return count, fmt.Errorf("set mode of '%s': %w", target, err)
+		}
+
+		metadata.restore(header, target)
+		count++
+	}
+
+	return count, nil
+}
+
 func CopyArtifact(r io.Reader, header *tar.Header, output string) error {
-	// Determine the target path
-	targetPath := output
+	return CopyEntry(r, header, ArtifactPath(header.Name, output))
+}
 
+// ArtifactPath returns the path an artifact is copied to: the output itself,
+// or the artifact's base name inside the output if it is a directory
+func ArtifactPath(name, output string) string {
 	// If output is a directory, use the artifact's name within that directory
-	if stat, err := os.Stat(output); err == nil && stat.IsDir() {
+	if IsDirTarget(output) {
 		// Use just the base name of the artifact, not the full path
-		targetPath = filepath.Join(output, filepath.Base(header.Name))
-	} else if strings.HasSuffix(output, "/") {
-		// If output ends with /, treat it as a directory even if it doesn't exist yet
-		targetPath = filepath.Join(output, filepath.Base(header.Name))
+		return filepath.Join(output, filepath.Base(name))
 	}
 
+	return output
+}
+
+// IsDirTarget checks if the output is an existing directory or ends with a slash,
+// so artifacts are written inside it
+func IsDirTarget(output string) bool {
+	if stat, err := os.Stat(output); err == nil && stat.IsDir() {
+		return true
+	}
+
+	return strings.HasSuffix(output, "/") || strings.HasSuffix(output, string(os.PathSeparator))
+}
+
+// StripComponents removes n leading components from the image path,
+// false if no components are left
+func StripComponents(name string, n int) (string, bool) {
+	name = CleanPath(name)
+	for i := 0; i < n; i++ {
+		_, rest, ok := strings.Cut(name, "/")
+		if !ok {
+			return "", false
+		}
+
+		name = rest
+	}
+
+	return name, name != ""
+}
+
+// isWithin checks if the path is inside the root directory
+func isWithin(root, p string) bool {
+	return strings.HasPrefix(p, filepath.Clean(root)+string(os.PathSeparator))
+}
+
+// LayoutTarget resolves the target of an entry written at its image path inside the output,
+// the layout cp --with-deps keeps. With strict, links leaving the output are rejected
+func LayoutTarget(output string, header *tar.Header, strict bool) (string, error) {
+	if strict {
+		if err := CheckLink(header); err != nil {
+			return "", err
+		}
+	}
+
+	return SecureJoin(output, header.Name)
+}
+
+// CopyLayoutEntry writes the entry to its target from LayoutTarget.
+// Hardlinks are linked to their target, which is at its image path inside the output too
+func CopyLayoutEntry(r io.Reader, header *tar.Header, output, target string) error {
+	if header.Typeflag != tar.TypeLink {
+		return CopyEntry(r, header, target)
+	}
+
+	source, err := SecureJoin(output, header.Linkname)
+	if err != nil {
+		return err
+	}
+
+	return LinkOrCopy(source, target)
+}
+
+// CopyEntry writes a single tar entry to the target path.
+// Hardlinks depend on where their target was copied and are created with LinkOrCopy
+func CopyEntry(r io.Reader, header *tar.Header, targetPath string) error {
 	switch header.Typeflag {
 	case tar.TypeReg:
 		if err := extractFile(r, header, targetPath); err != nil {
@@ -120,10 +462,12 @@ func CopyArtifact(r io.Reader, header *tar.Header, output string) error {
 		if err := extractDir(header, targetPath); err != nil {
 			return fmt.Errorf("extract directory '%s': %w", header.Name, err)
 		}
-	case tar.TypeSymlink, tar.TypeLink:
+	case tar.TypeSymlink:
 		if err := extractLink(header, targetPath); err != nil {
 			return fmt.Errorf("extract the link '%s': %w", header.Name, err)
 		}
+	case tar.TypeLink:
+		return fmt.Errorf("hardlink '%s' can not be copied without its target", header.Name)
 	}
 
 	return nil
@@ -136,6 +480,13 @@ func extractFile(reader io.Reader, header *tar.Header, targetPath string) error
 		return fmt.Errorf("create the target dir '%s': %w", targetDir, err)
 	}
 
+	// Writing through an existing symlink would change its target instead
+	if stat, err := os.Lstat(targetPath); err == nil && stat.Mode()&os.ModeSymlink != 0 {
+		if err = os.Remove(targetPath); err != nil {
+			return fmt.Errorf("remove the existing link '%s': %w", targetPath, err)
+		}
+	}
+
 	target, err := os.OpenFile(targetPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
 	if err != nil {
 		return fmt.Errorf("create the target file '%s': %w", targetPath, err)
@@ -151,6 +502,13 @@ func extractFile(reader io.Reader, header *tar.Header, targetPath string) error
 
 // extractDir creates the target directory structure
 func extractDir(header *tar.Header, targetPath string) error {
+	// A directory replaces a file or a symlink, which could lead outside
+	if stat, err := os.Lstat(targetPath); err == nil && !stat.IsDir() {
+		if err = os.Remove(targetPath); err != nil {
+			return fmt.Errorf("remove the existing entry '%s': %w", targetPath, err)
+		}
+	}
+
 	if err := os.MkdirAll(targetPath, os.FileMode(header.Mode)); err != nil {
 		return fmt.Errorf("create the target dir '%s': %w", targetPath, err)
 	}
@@ -158,8 +516,63 @@ func extractDir(header *tar.Header, targetPath string) error {
 	return nil
 }
 
-// extractLink creates symbolic or hard links
+// extractLink creates symbolic links
 func extractLink(header *tar.Header, targetPath string) error {
+	if err := prepareTarget(targetPath); err != nil {
+		return err
+	}
+
+	if err := os.Symlink(header.Linkname, targetPath); err != nil {
+		return fmt.Errorf("create symlink '%s' -> '%s': %w", targetPath, header.Linkname, err)
+	}
+
+	return nil
+}
+
+// linkFile creates a hardlink, replaced in tests to take the copy fallback of LinkOrCopy
+var linkFile = os.Link
+
+// LinkOrCopy creates a hardlink to the source file, or a copy of it when
+// hardlinks are not possible, e.g. across filesystems
+func LinkOrCopy(source, target string) error {
+	if err := prepareTarget(target); err != nil {
+		return err
+	}
+
+	if err := linkFile(source, target); err == nil {
+		return nil
+	}
+
+	stat, err := os.Lstat(source)
+	if err != nil {
+		return fmt.Errorf("hardlink target '%s' is not extracted: %w", source, err)
+	}
+
+	if !stat.Mode().IsRegular() {
+		return fmt.Errorf("hardlink target '%s' is not a regular file", source)
+	}
+
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("open the hardlink target '%s': %w", source, err)
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, stat.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("create the copy '%s': %w", target, err)
+	}
+	defer out.Close()
+
+	if _, err = io.Copy(out, in); err != nil {
+		return fmt.Errorf("copy '%s' to '%s': %w", source, target, err)
+	}
+
+	return nil
+}
+
+// prepareTarget creates the parent directory of a link and removes an existing entry
+func prepareTarget(targetPath string) error {
 	targetDir := filepath.Dir(targetPath)
 	if err := os.MkdirAll(targetDir, 0755); err != nil {
 		return fmt.Errorf("create the target dir '%s': %w", targetDir, err)
@@ -172,23 +585,5 @@ func extractLink(header *tar.Header, targetPath string) error {
 		}
 	}
 
-	if header.Typeflag == tar.TypeSymlink {
-		if err := os.Symlink(header.Linkname, targetPath); err != nil {
-			return fmt.Errorf("create symlink '%s' -> '%s': %w", targetPath, header.Linkname, err)
-		}
-
-		return nil
-	}
-
-	if header.Typeflag == tar.TypeLink {
-		// For hard links, we need the target to be relative to our extraction directory
-		linkTarget := filepath.Join(filepath.Dir(targetPath), header.Linkname)
-		if err := os.Link(linkTarget, targetPath); err != nil {
-			// If hard link fails, create a copy instead
-			fmt.Printf("Warning: Could not create hard link, creating copy instead: %s -> %s\n", targetPath, header.Linkname)
-			return nil
-		}
-	}
-
 	return nil
 }
@@ -0,0 +1,264 @@
+package tools
+
+import (
+	"archive/tar"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// listOutput returns the paths inside the output directory
+func listOutput(t *testing.T, output string) []string {
+	t.Helper()
+
+	var paths []string
+	err := filepath.WalkDir(output, func(p string, _ fs.DirEntry, err error) error {
+		if err != nil || p == output {
+			return err
+		}
+
+		rel, err := filepath.Rel(output, p)
+		paths = append(paths, filepath.ToSlash(rel))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return paths
+}
+
+func mustMatchers(t *testing.T, patterns ...string) []*Matcher {
+	t.Helper()
+
+	matchers, err := NewMatchers(patterns, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return matchers
+}
+
+func TestCopyTarFilters(t *testing.T) {
+	headers := []*tar.Header{
+		{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "etc/nginx/", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "etc/nginx/nginx.conf", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1},
+		{Name: "etc/nginx/conf.d/default.conf", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1},
+		{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1},
+		{Name: "usr/bin/nginx", Typeflag: tar.TypeReg, Mode: 0o755, Size: 1},
+	}
+
+	tests := []struct {
+		name        string
+		include     []string
+		exclude     []string
+		want        []string
+		wantSkipped int64
+	}{
+		{
+			name: "everything",
+			want: []string{"etc", "etc/nginx", "etc/nginx/conf.d", "etc/nginx/conf.d/default.conf",
+				"etc/nginx/nginx.conf", "etc/passwd", "usr", "usr/bin", "usr/bin/nginx"},
+		},
+		{
+			name:    "include a directory",
+			include: []string{"etc/nginx"},
+			want: []string{"etc", "etc/nginx", "etc/nginx/conf.d", "etc/nginx/conf.d/default.conf",
+				"etc/nginx/nginx.conf"},
+			wantSkipped: 3,
+		},
+		{
+			name:        "exclude a glob",
+			exclude:     []string{"**/*.conf"},
+			want:        []string{"etc", "etc/nginx", "etc/passwd", "usr", "usr/bin", "usr/bin/nginx"},
+			wantSkipped: 2,
+		},
+		{
+			name:        "exclude inside an include",
+			include:     []string{"etc"},
+			exclude:     []string{"etc/nginx/conf.d"},
+			want:        []string{"etc", "etc/nginx", "etc/nginx/nginx.conf", "etc/passwd"},
+			wantSkipped: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := t.TempDir()
+			opts := &ExtractOptions{Include: mustMatchers(t, tt.include...), Exclude: mustMatchers(t, tt.exclude...)}
+
+			res, err := CopyTar(context.Background(), buildTarHeaders(t, headers...), output, opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := listOutput(t, output); !slices.Equal(got, tt.want) {
+				t.Errorf("extracted %v, want %v", got, tt.want)
+			}
+
+			if res.EntriesSkipped != tt.wantSkipped {
+				t.Errorf("skipped %d entries, want %d", res.EntriesSkipped, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestCopyTarFilterParentModes(t *testing.T) {
+	// Only the files are selected, the parents are created with the modes of their image entries
+	headers := []*tar.Header{
+		{Name: "before/", Typeflag: tar.TypeDir, Mode: 0o700},
+		{Name: "before/file", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1},
+		{Name: "after/sub/file", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1},
+		{Name: "after/sub/", Typeflag: tar.TypeDir, Mode: 0o750},
+		{Name: "after/", Typeflag: tar.TypeDir, Mode: 0o711},
+	}
+
+	output := t.TempDir()
+	opts := &ExtractOptions{Include: mustMatchers(t, "**/file")}
+	if _, err := CopyTar(context.Background(), buildTarHeaders(t, headers...), output, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	modes := map[string]os.FileMode{"before": 0o700, "after": 0o711, "after/sub": 0o750}
+	for name, want := range modes {
+		stat, err := os.Stat(filepath.Join(output, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := stat.Mode().Perm(); got != want {
+			t.Errorf("'%s' has mode %04o, want %04o", name, got, want)
+		}
+	}
+}
+
+func TestCopyTarExcludedHardlinkTarget(t *testing.T) {
+	headers := []*tar.Header{
+		{Name: "bin/", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "bin/busybox", Typeflag: tar.TypeReg, Mode: 0o755, Size: 8},
+		{Name: "bin/sh", Typeflag: tar.TypeLink, Linkname: "bin/busybox"},
+		{Name: "bin/ls", Typeflag: tar.TypeLink, Linkname: "bin/busybox"},
+	}
+
+	output := t.TempDir()
+	opts := &ExtractOptions{Exclude: mustMatchers(t, "bin/busybox")}
+	res, err := CopyTar(context.Background(), buildTarHeaders(t, headers...), output, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := map[string][]string{"bin/busybox": {filepath.Join(output, "bin", "sh"), filepath.Join(output, "bin", "ls")}}
+	if len(res.PendingLinks) != 1 || !slices.Equal(res.PendingLinks["bin/busybox"], want["bin/busybox"]) {
+		t.Fatalf("pending links %v, want %v", res.PendingLinks, want)
+	}
+
+	// The content of the target is read from a new stream of the image
+	if err = CopyLinkTargets(buildTarHeaders(t, headers...), res.PendingLinks); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := listOutput(t, output); !slices.Equal(got, []string{"bin", "bin/ls", "bin/sh"}) {
+		t.Errorf("extracted %v, want the links without their target", got)
+	}
+
+	for _, name := range []string{"sh", "ls"} {
+		content, err := os.ReadFile(filepath.Join(output, "bin", name))
+		if err != nil || string(content) != "xxxxxxxx" {
+			t.Errorf("'bin/%s' has content %q, %v", name, content, err)
+		}
+	}
+}
+
+func TestCopyLinkTargetsNotFound(t *testing.T) {
+	headers := []*tar.Header{
+		{Name: "bin/", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "bin/busybox", Typeflag: tar.TypeReg, Mode: 0o755, Size: 8},
+	}
+
+	output := t.TempDir()
+	for _, name := range []string{"bin/missing", "bin"} {
+		links := map[string][]string{name: {filepath.Join(output, "link")}}
+
+		err := CopyLinkTargets(buildTarHeaders(t, headers...), links)
+		if err == nil || !strings.Contains(err.Error(), "hardlink target '"+name+"' is not found") {
+			t.Errorf("CopyLinkTargets(%s) error = %v, want not found", name, err)
+		}
+	}
+}
+
+// hardlinkHeaders is an image with hardlinks in other directories than their target.
+// Hardlink names are relative to the archive root, not to the directory of the link
+var hardlinkHeaders = []*tar.Header{
+	{Name: "usr/", Typeflag: tar.TypeDir, Mode: 0o755},
+	{Name: "usr/bin/", Typeflag: tar.TypeDir, Mode: 0o755},
+	{Name: "usr/bin/python3.11", Typeflag: tar.TypeReg, Mode: 0o755, Size: 16},
+	{Name: "usr/local/bin/python", Typeflag: tar.TypeLink, Linkname: "usr/bin/python3.11"},
+	{Name: "./python", Typeflag: tar.TypeLink, Linkname: "./usr/bin/python3.11"},
+}
+
+func TestCopyTarHardlinkInOtherDirectory(t *testing.T) {
+	tests := []struct {
+		name string
+		// fallback makes hardlinks fail, e.g. across filesystems, so the target is copied
+		fallback bool
+		// layout writes the entries the way cp --with-deps does
+		layout bool
+	}{
+		{name: "hardlink"},
+		{name: "copy fallback", fallback: true},
+		{name: "layout hardlink", layout: true},
+		{name: "layout copy fallback", layout: true, fallback: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.fallback {
+				linkFile = func(string, string) error { return &os.LinkError{Op: "link", Err: os.ErrPermission} }
+				t.Cleanup(func() { linkFile = os.Link })
+			}
+
+			output := t.TempDir()
+
+			var err error
+			if tt.layout {
+				err = copyLayout(buildTarHeaders(t, hardlinkHeaders...), output, true)
+			} else {
+				_, err = CopyTar(context.Background(), buildTarHeaders(t, hardlinkHeaders...), output, &ExtractOptions{Strict: true})
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			target := filepath.Join(output, "usr", "bin", "python3.11")
+			targetStat, err := os.Stat(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, link := range []string{filepath.Join(output, "usr", "local", "bin", "python"), filepath.Join(output, "python")} {
+				stat, err := os.Lstat(link)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if same := os.SameFile(stat, targetStat); same == tt.fallback {
+					t.Errorf("'%s' is the same file as its target: %v, want %v", link, same, !tt.fallback)
+				}
+
+				if !stat.Mode().IsRegular() || stat.Mode().Perm() != targetStat.Mode().Perm() {
+					t.Errorf("'%s' has mode %v, want %v", link, stat.Mode(), targetStat.Mode())
+				}
+
+				content, err := os.ReadFile(link)
+				if err != nil || string(content) != strings.Repeat("x", 16) {
+					t.Errorf("'%s' has content %q, %v", link, content, err)
+				}
+			}
+		})
+	}
+}
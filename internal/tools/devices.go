@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// DeviceManifest records special files that could not be created without privileges
+// as a shell script, so a later privileged step can recreate them inside the output
+type DeviceManifest struct {
+	entries []deviceEntry
+}
+
+// deviceEntry is a recorded special file and its path relative to the output
+type deviceEntry struct {
+	header *tar.Header
+	path   string
+}
+
+// manifestCheck is the script function refusing paths with a symlink among their parents,
+// which could lead mknod and chown outside of the directory when the script runs as root
+const manifestCheck = `check() {
+	dir=$(dirname -- "$1")
+	while [ "$dir" != "." ] && [ "$dir" != "/" ]; do
+		if [ -L "$dir" ]; then
+			echo "refusing '$1': '$dir' is a symlink" >&2
+			exit 1
+		fi
+		dir=$(dirname -- "$dir")
+	done
+}
+`
+
+// Add records the special file at its path relative to the output, resolved with SecureJoin
+func (m *DeviceManifest) Add(header *tar.Header, path string) {
+	m.entries = append(m.entries, deviceEntry{header: header, path: path})
+}
+
+// Len returns the number of recorded special files
+func (m *DeviceManifest) Len() int {
+	return len(m.entries)
+}
+
+// Write writes the manifest as a script creating the files relative to the directory
+// passed as its argument, the current one by default
+func (m *DeviceManifest) Write(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString("# Special files that need privileges to be created, run as root:\n")
+	b.WriteString("#   sh <manifest> <extracted directory>\n")
+	b.WriteString("set -e\n")
+	b.WriteString("cd \"${1:-.}\"\n\n")
+	b.WriteString(manifestCheck)
+
+	for _, entry := range m.entries {
+		header := entry.header
+		name := shellQuote(entry.path)
+		mode := header.Mode & 07777
+
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "check %s\n", name)
+		switch header.Typeflag {
+		case tar.TypeChar:
+			fmt.Fprintf(&b, "mknod -m %04o -- %s c %d %d\n", mode, name, header.Devmajor, header.Devminor)
+		case tar.TypeBlock:
+			fmt.Fprintf(&b, "mknod -m %04o -- %s b %d %d\n", mode, name, header.Devmajor, header.Devminor)
+		case tar.TypeFifo:
+			fmt.Fprintf(&b, "mkfifo -m %04o -- %s\n", mode, name)
+		}
+		// -h changes a symlink at the final component instead of the file it points to
+		fmt.Fprintf(&b, "chown -h -- %d:%d %s\n", header.Uid, header.Gid, name)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteFile writes the manifest script to the path
+func (m *DeviceManifest) WriteFile(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return fmt.Errorf("create the devices manifest: %w", err)
+	}
+	defer f.Close()
+
+	if err = m.Write(f); err != nil {
+		return fmt.Errorf("write the devices manifest '%s': %w", path, err)
+	}
+
+	return nil
+}
+
+// IsSpecialFile checks if the entry is a device node or a FIFO
+func IsSpecialFile(header *tar.Header) bool {
+	return header.Typeflag == tar.TypeChar || header.Typeflag == tar.TypeBlock || header.Typeflag == tar.TypeFifo
+}
+
+// extractSpecialFile creates a device node or a FIFO with the exact permissions of the entry
+func extractSpecialFile(header *tar.Header, targetPath string) error {
+	if err := prepareTarget(targetPath); err != nil {
+		return err
+	}
+
+	if err := mknod(targetPath, header); err != nil {
+		return fmt.Errorf("create the special file '%s': %w", targetPath, err)
+	}
+
+	// The permissions of device nodes matter, they are not left to the umask
+	if err := os.Chmod(targetPath, fileMode(header)); err != nil {
+		return fmt.Errorf("set mode of '%s': %w", targetPath, err)
+	}
+
+	return nil
+}
+
+// needsPrivileges checks if the special file could not be created for lack of privileges
+func needsPrivileges(err error) bool {
+	return errors.Is(err, fs.ErrPermission) || errors.Is(err, errors.ErrUnsupported)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
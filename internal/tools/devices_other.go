@@ -0,0 +1,13 @@
+//go:build !unix
+
+package tools
+
+import (
+	"archive/tar"
+	"errors"
+)
+
+// mknod is not supported, the special files are recorded in the manifest
+func mknod(_ string, _ *tar.Header) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package tools
+
+import (
+	"archive/tar"
+
+	"golang.org/x/sys/unix"
+)
+
+// mknod creates the device node or the FIFO, device nodes usually require root
+func mknod(path string, header *tar.Header) error {
+	mode := uint32(header.Mode & 07777)
+
+	switch header.Typeflag {
+	case tar.TypeChar:
+		mode |= unix.S_IFCHR
+	case tar.TypeBlock:
+		mode |= unix.S_IFBLK
+	default:
+		return unix.Mkfifo(path, mode)
+	}
+
+	dev := unix.Mkdev(uint32(header.Devmajor), uint32(header.Devminor))
+	return unix.Mknod(path, mode, int(dev))
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"debug/elf"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ELFInfo contains the dynamic linking information of an ELF object
+type ELFInfo struct {
+	Class       elf.Class
+	Machine     elf.Machine
+	Interpreter string
+	Needed      []string
+	RunPath     []string
+	RPath       []string
+}
+
+// Compatible checks if both objects can be loaded into the same process
+func (i *ELFInfo) Compatible(other *ELFInfo) bool {
+	return i.Class == other.Class && i.Machine == other.Machine
+}
+
+// ReadELF reads the dynamic linking information of an ELF object
+func ReadELF(r io.ReaderAt) (*ELFInfo, error) {
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return nil, fmt.Errorf("parse ELF: %w", err)
+	}
+	defer f.Close()
+
+	info := &ELFInfo{
+		Class:   f.Class,
+		Machine: f.Machine,
+	}
+
+	for _, prog := range f.Progs {
+		if prog.Type != elf.PT_INTERP {
+			continue
+		}
+
+		interp, err := io.ReadAll(prog.Open())
+		if err != nil {
+			return nil, fmt.Errorf("read ELF interpreter: %w", err)
+		}
+
+		info.Interpreter = strings.TrimRight(string(interp), "\x00")
+	}
+
+	// Statically linked objects have no dynamic section
+	if f.Section(".dynamic") == nil {
+		return info, nil
+	}
+
+	if info.Needed, err = f.DynString(elf.DT_NEEDED); err != nil {
+		return nil, fmt.Errorf("read DT_NEEDED: %w", err)
+	}
+
+	runPath, err := f.DynString(elf.DT_RUNPATH)
+	if err != nil {
+		return nil, fmt.Errorf("read DT_RUNPATH: %w", err)
+	}
+	info.RunPath = splitSearchPath(runPath)
+
+	rPath, err := f.DynString(elf.DT_RPATH)
+	if err != nil {
+		return nil, fmt.Errorf("read DT_RPATH: %w", err)
+	}
+	info.RPath = splitSearchPath(rPath)
+
+	return info, nil
+}
+
+// splitSearchPath splits colon separated search paths
+func splitSearchPath(values []string) []string {
+	var res []string
+	for _, value := range values {
+		for _, p := range strings.Split(value, ":") {
+			if p != "" {
+				res = append(res, p)
+			}
+		}
+	}
+
+	return res
+}
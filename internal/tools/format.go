@@ -2,6 +2,8 @@ package tools
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 )
 
 const unit = 1024
@@ -20,3 +22,26 @@ func FormatSize(bytes int64) string {
 
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
+
+// ParseSize parses human-readable sizes like '512', '10KB', '1.5 MB' or '2GiB' (1024-based, as FormatSize)
+func ParseSize(s string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(s))
+	value = strings.TrimSuffix(strings.TrimSuffix(value, "IB"), "B")
+
+	multiplier := int64(1)
+	if value != "" {
+		if exp := strings.IndexByte("KMGTPE", value[len(value)-1]); exp >= 0 {
+			for i := 0; i <= exp; i++ {
+				multiplier *= unit
+			}
+			value = value[:len(value)-1]
+		}
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", s)
+	}
+
+	return int64(number * float64(multiplier)), nil
+}
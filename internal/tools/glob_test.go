@@ -0,0 +1,58 @@
+package tools
+
+import "testing"
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		// '**' matches any number of path components, including none
+		{pattern: "**/*.so", name: "libc.so", want: true},
+		{pattern: "**/*.so", name: "usr/lib/x86_64/libc.so", want: true},
+		{pattern: "usr/**", name: "usr/lib/libc.so", want: true},
+		{pattern: "usr/**/libc.so", name: "usr/libc.so", want: true},
+		{pattern: "usr/**/libc.so", name: "usr/lib/x86_64/libc.so", want: true},
+		{pattern: "usr/**/libc.so", name: "opt/usr/lib/libc.so", want: false},
+
+		// '*' stays within a single component
+		{pattern: "etc/*.conf", name: "etc/app.conf", want: true},
+		{pattern: "etc/*.conf", name: "etc/app/app.conf", want: false},
+		{pattern: "*", name: "etc/passwd", want: false},
+		{pattern: "/etc/*", name: "etc/passwd", want: true},
+
+		// '?' matches exactly one character, but not '/'
+		{pattern: "bin/s?", name: "bin/sh", want: true},
+		{pattern: "bin/s?", name: "bin/ssh", want: false},
+		{pattern: "bin?sh", name: "bin/sh", want: false},
+
+		// Character classes, negated with '!'
+		{pattern: "lib[0-9].so", name: "lib6.so", want: true},
+		{pattern: "lib[0-9].so", name: "libc.so", want: false},
+		{pattern: "lib[!0-9].so", name: "libc.so", want: true},
+		{pattern: "lib[!0-9].so", name: "lib6.so", want: false},
+
+		// Escapes and regexp meta characters are literal
+		{pattern: `file\*.txt`, name: "file*.txt", want: true},
+		{pattern: `file\*.txt`, name: "file1.txt", want: false},
+		{pattern: "a+b.txt", name: "a+b.txt", want: true},
+		{pattern: "a+b.txt", name: "aab.txt", want: false},
+	}
+
+	for _, tt := range tests {
+		re, err := CompileGlob(tt.pattern)
+		if err != nil {
+			t.Errorf("CompileGlob(%q) failed: %v", tt.pattern, err)
+			continue
+		}
+
+		if got := re.MatchString(tt.name); got != tt.want {
+			t.Errorf("CompileGlob(%q) matches %q = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := CompileGlob("lib[0-9.so"); err == nil {
+		t.Errorf("CompileGlob() accepted an unterminated character class")
+	}
+}
@@ -0,0 +1,286 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// LayerChanges describes what a single layer changes in the filesystem below it
+type LayerChanges struct {
+	// Entries contains files, directories and links added or replaced by the layer
+	Entries map[string]*tar.Header
+	// Deleted contains paths removed by whiteout files
+	Deleted []string
+	// Opaque contains directories whose lower layer contents are hidden
+	Opaque []string
+}
+
+// NewLayerChanges creates empty layer changes
+func NewLayerChanges() *LayerChanges {
+	return &LayerChanges{Entries: make(map[string]*tar.Header)}
+}
+
+// ReadLayer walks over an uncompressed layer tar, interpreting whiteouts.
+// The function is called for every entry except whiteout markers, which are recorded in the result
+func ReadLayer(rc io.ReadCloser, f func(r io.Reader, header *tar.Header) error) (*LayerChanges, error) {
+	changes := NewLayerChanges()
+
+	reader := tar.NewReader(rc)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read tar header: %w", err)
+		}
+
+		if dir, ok := OpaqueDir(header.Name); ok {
+			changes.Opaque = append(changes.Opaque, dir)
+			continue
+		}
+
+		if target, ok := WhiteoutTarget(header.Name); ok {
+			changes.Deleted = append(changes.Deleted, target)
+			continue
+		}
+
+		changes.Entries[CleanPath(header.Name)] = header
+
+		if f == nil {
+			continue
+		}
+
+		if err = f(reader, header); err != nil {
+			if errors.Is(err, ErrStopWalk) {
+				break
+			}
+
+			return nil, err
+		}
+	}
+
+	sort.Strings(changes.Deleted)
+	sort.Strings(changes.Opaque)
+
+	return changes, nil
+}
+
+// Hides checks if the path from a lower layer is replaced or removed by this layer
+func (l *LayerChanges) Hides(p string) bool {
+	p = CleanPath(p)
+
+	if _, ok := l.Entries[p]; ok {
+		return true
+	}
+
+	for _, deleted := range l.Deleted {
+		if p == deleted || strings.HasPrefix(p, deleted+"/") {
+			return true
+		}
+	}
+
+	for _, dir := range l.Opaque {
+		if dir == "" || strings.HasPrefix(p, dir+"/") {
+			return true
+		}
+	}
+
+	// A file or a link replacing a lower directory hides its contents
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		if header, ok := l.Entries[dir]; ok && header.Typeflag != tar.TypeDir {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RemoveHidden applies the layer to the merged filesystem of the layers below it, keyed by
+// cleaned paths: lower paths removed by whiteouts or opaque directories, and the contents of
+// directories the layer replaces with a file or a link, are passed to removed and deleted.
+// Paths the layer provides itself are kept, the caller replaces them with changes.Entries
+func RemoveHidden[T any](merged map[string]T, changes *LayerChanges, removed func(p string, lower T)) {
+	hides := len(changes.Deleted) > 0 || len(changes.Opaque) > 0
+	for _, header := range changes.Entries {
+		if hides {
+			break
+		}
+		hides = header.Typeflag != tar.TypeDir
+	}
+
+	if !hides {
+		return
+	}
+
+	for p, lower := range merged {
+		if _, replaced := changes.Entries[p]; replaced || !changes.Hides(p) {
+			continue
+		}
+
+		if removed != nil {
+			removed(p, lower)
+		}
+		delete(merged, p)
+	}
+}
+
+// LayerOpener opens the uncompressed tar stream of a layer
+type LayerOpener func() (io.ReadCloser, error)
+
+// mergedPaths tracks paths provided or removed by the upper layers while merging layers from the top
+type mergedPaths struct {
+	seen    map[string]bool // Visited paths, true for directories
+	deleted map[string]bool
+	opaque  map[string]bool
+}
+
+// hidden checks if the path from a lower layer is replaced or removed by the upper layers
+func (m *mergedPaths) hidden(p string) bool {
+	if _, ok := m.seen[p]; ok || m.deleted[p] || m.opaque[""] {
+		return true
+	}
+
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		// A non-directory in an upper layer replaces the whole lower directory
+		if isDir, ok := m.seen[dir]; (ok && !isDir) || m.deleted[dir] || m.opaque[dir] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WalkLayers walks over the merged filesystem of the layers ordered from the base layer up.
+// Layers are read from the top, so files replaced or removed by whiteouts and opaque
+// directories of upper layers are never visited. Names are cleaned, and hardlinks
+// are visited last, after the files they point to
+func WalkLayers(layers []LayerOpener, f func(r io.Reader, header *tar.Header) error) error {
+	merged := &mergedPaths{
+		seen:    make(map[string]bool),
+		deleted: make(map[string]bool),
+		opaque:  make(map[string]bool),
+	}
+
+	var links []*tar.Header
+	for i := len(layers) - 1; i >= 0; i-- {
+		stop, err := walkMergedLayer(layers[i], merged, &links, f)
+		if err != nil {
+			return fmt.Errorf("walk layer %d: %w", i, err)
+		}
+		if stop {
+			return nil
+		}
+	}
+
+	for _, link := range links {
+		if err := f(bytes.NewReader(nil), link); err != nil {
+			if errors.Is(err, ErrStopWalk) {
+				return nil
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walkMergedLayer visits the entries of a single layer not hidden by the upper layers.
+// Whiteouts of the layer apply only to the layers below it
+func walkMergedLayer(open LayerOpener, merged *mergedPaths, links *[]*tar.Header, f func(r io.Reader, header *tar.Header) error) (bool, error) {
+	rc, err := open()
+	if err != nil {
+		return false, fmt.Errorf("open layer: %w", err)
+	}
+	defer rc.Close()
+
+	var deleted, opaque []string
+
+	reader := tar.NewReader(rc)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, fmt.Errorf("read tar header: %w", err)
+		}
+
+		if dir, ok := OpaqueDir(header.Name); ok {
+			opaque = append(opaque, dir)
+			continue
+		}
+
+		if target, ok := WhiteoutTarget(header.Name); ok {
+			deleted = append(deleted, target)
+			continue
+		}
+
+		p := CleanPath(header.Name)
+		if p == "" || merged.hidden(p) {
+			continue
+		}
+		merged.seen[p] = header.Typeflag == tar.TypeDir
+
+		header.Name = p
+		if header.Typeflag == tar.TypeLink {
+			header.Linkname = CleanPath(header.Linkname)
+			*links = append(*links, header)
+			continue
+		}
+
+		if err = f(reader, header); err != nil {
+			if errors.Is(err, ErrStopWalk) {
+				return true, nil
+			}
+
+			return false, err
+		}
+	}
+
+	for _, p := range deleted {
+		merged.deleted[p] = true
+	}
+	for _, dir := range opaque {
+		merged.opaque[dir] = true
+	}
+
+	return false, nil
+}
+
+// FlattenLayers returns the merged filesystem of the layers as a single tar stream without whiteouts
+func FlattenLayers(layers []LayerOpener) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		writer := tar.NewWriter(pw)
+
+		err := WalkLayers(layers, func(r io.Reader, header *tar.Header) error {
+			// PAX removes the name length limit of USTAR
+			header.Format = tar.FormatPAX
+			if err := writer.WriteHeader(header); err != nil {
+				return fmt.Errorf("write tar header: %w", err)
+			}
+
+			if _, err := io.Copy(writer, r); err != nil {
+				return fmt.Errorf("write '%s': %w", header.Name, err)
+			}
+
+			return nil
+		})
+		if err == nil {
+			err = writer.Close()
+		}
+
+		pw.CloseWithError(err)
+	}()
+
+	return pr
+}
@@ -0,0 +1,257 @@
+package tools
+
+import (
+	"archive/tar"
+	"io"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestRemoveHidden(t *testing.T) {
+	lower := []string{"etc", "etc/app", "etc/app/config", "etc/app/certs/ca.pem", "opt/tool", "usr/lib/libfoo.so", "var/cache/a.bin"}
+
+	tests := []struct {
+		name    string
+		changes *LayerChanges
+		removed []string
+	}{
+		{
+			name:    "whiteout of a directory",
+			changes: &LayerChanges{Entries: map[string]*tar.Header{}, Deleted: []string{"etc/app"}},
+			removed: []string{"etc/app", "etc/app/certs/ca.pem", "etc/app/config"},
+		},
+		{
+			name:    "opaque directory",
+			changes: &LayerChanges{Entries: map[string]*tar.Header{"var/cache/b.bin": {Typeflag: tar.TypeReg}}, Opaque: []string{"var/cache"}},
+			removed: []string{"var/cache/a.bin"},
+		},
+		{
+			name: "directory replaced by a symlink",
+			changes: &LayerChanges{Entries: map[string]*tar.Header{
+				"etc/app": {Typeflag: tar.TypeSymlink, Linkname: "/srv/app"},
+			}},
+			removed: []string{"etc/app/certs/ca.pem", "etc/app/config"},
+		},
+		{
+			name: "directory replaced by a file",
+			changes: &LayerChanges{Entries: map[string]*tar.Header{
+				"etc": {Typeflag: tar.TypeReg},
+			}},
+			removed: []string{"etc/app", "etc/app/certs/ca.pem", "etc/app/config"},
+		},
+		{
+			name: "files replaced in place",
+			changes: &LayerChanges{Entries: map[string]*tar.Header{
+				"opt/tool":       {Typeflag: tar.TypeReg},
+				"etc/app":        {Typeflag: tar.TypeDir},
+				"etc/app/config": {Typeflag: tar.TypeReg},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := make(map[string]bool)
+			for _, p := range lower {
+				merged[p] = true
+			}
+
+			var removed []string
+			RemoveHidden(merged, tt.changes, func(p string, _ bool) {
+				removed = append(removed, p)
+			})
+			slices.Sort(removed)
+
+			if !slices.Equal(removed, tt.removed) {
+				t.Errorf("removed %v, want %v", removed, tt.removed)
+			}
+
+			for _, p := range removed {
+				if merged[p] {
+					t.Errorf("'%s' is still merged", p)
+				}
+			}
+
+			if want := len(lower) - len(tt.removed); len(merged) != want {
+				t.Errorf("%d paths left, want %d: %v", len(merged), want, slices.Sorted(maps.Keys(merged)))
+			}
+		})
+	}
+}
+
+// layerTar opens the entries as a layer, counting how often it is opened
+func layerTar(t *testing.T, opened *int, entries ...tarEntry) LayerOpener {
+	return func() (io.ReadCloser, error) {
+		if opened != nil {
+			*opened++
+		}
+
+		return buildTar(t, entries...), nil
+	}
+}
+
+// describeEntry formats the entry as 'path', 'path=content' for files or 'path->link' for links
+func describeEntry(r io.Reader, header *tar.Header) (string, error) {
+	switch header.Typeflag {
+	case tar.TypeReg:
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return "", err
+		}
+
+		return header.Name + "=" + string(content), nil
+	case tar.TypeSymlink, tar.TypeLink:
+		return header.Name + "->" + header.Linkname, nil
+	}
+
+	return header.Name, nil
+}
+
+func TestWalkLayers(t *testing.T) {
+	tests := []struct {
+		name   string
+		layers [][]tarEntry
+		want   []string
+	}{
+		{
+			name: "upper layer replaces files",
+			layers: [][]tarEntry{
+				{tarDir("bin/"), tarFile("bin/sh", "v1"), tarFile("bin/ls", "v1")},
+				{tarFile("bin/sh", "v2")},
+			},
+			want: []string{"bin", "bin/ls=v1", "bin/sh=v2"},
+		},
+		{
+			name: "whiteouts apply only to lower layers",
+			layers: [][]tarEntry{
+				{tarDir("etc/"), tarFile("etc/old", "x"), tarFile("bin/sh", "x")},
+				{tarFile(".wh.etc", ""), tarDir("etc/"), tarFile("etc/new", "x"), tarFile("bin/.wh.sh", "")},
+			},
+			want: []string{"etc", "etc/new=x"},
+		},
+		{
+			name: "opaque directory",
+			layers: [][]tarEntry{
+				{tarDir("var/"), tarDir("var/cache/"), tarFile("var/cache/a", "x"), tarFile("var/cache/sub/b", "x"), tarFile("var/log", "x")},
+				{tarDir("var/cache/"), tarFile("var/cache/.wh..wh..opq", ""), tarFile("var/cache/c", "x")},
+			},
+			want: []string{"var", "var/cache", "var/cache/c=x", "var/log=x"},
+		},
+		{
+			name: "names containing .wh. are regular files",
+			layers: [][]tarEntry{
+				{tarDir("etc/"), tarFile("etc/config.wh.json", "{}")},
+				{tarFile("etc/app.wh", "x")},
+			},
+			want: []string{"etc", "etc/app.wh=x", "etc/config.wh.json={}"},
+		},
+		{
+			name: "file replacing a lower directory",
+			layers: [][]tarEntry{
+				{tarDir("opt/"), tarDir("opt/app/"), tarFile("opt/app/bin", "x"), tarFile("opt/app/lib/libfoo.so", "x")},
+				{tarSymlink("opt/app", "/srv/app")},
+			},
+			want: []string{"opt", "opt/app->/srv/app"},
+		},
+		{
+			name: "names are cleaned",
+			layers: [][]tarEntry{
+				{tarDir("./"), tarDir("./usr/"), tarFile("./usr/bin", "x"), tarHardlink("./usr/link", "./usr/bin")},
+			},
+			want: []string{"usr", "usr/bin=x", "usr/link->usr/bin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var layers []LayerOpener
+			for _, entries := range tt.layers {
+				layers = append(layers, layerTar(t, nil, entries...))
+			}
+
+			var got []string
+			err := WalkLayers(layers, func(r io.Reader, header *tar.Header) error {
+				entry, err := describeEntry(r, header)
+				got = append(got, entry)
+				return err
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			slices.Sort(got)
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("visited %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWalkLayersHardlinksLast(t *testing.T) {
+	layers := []LayerOpener{
+		layerTar(t, nil, tarDir("usr/"), tarFile("usr/python3", "x")),
+		layerTar(t, nil, tarHardlink("usr/python", "usr/python3"), tarFile("usr/pip", "x")),
+	}
+
+	var got []string
+	err := WalkLayers(layers, func(r io.Reader, header *tar.Header) error {
+		got = append(got, header.Name)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The link of the upper layer comes after its target from the lower layer
+	if want := []string{"usr/pip", "usr", "usr/python3", "usr/python"}; !slices.Equal(got, want) {
+		t.Errorf("visited %v, want %v", got, want)
+	}
+}
+
+func TestWalkLayersStop(t *testing.T) {
+	var lowerOpened int
+	layers := []LayerOpener{
+		layerTar(t, &lowerOpened, tarFile("lower", "x")),
+		layerTar(t, nil, tarFile("a", "x"), tarFile("b", "x"), tarHardlink("c", "a")),
+	}
+
+	var got []string
+	err := WalkLayers(layers, func(r io.Reader, header *tar.Header) error {
+		got = append(got, header.Name)
+		return ErrStopWalk
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := []string{"a"}; !slices.Equal(got, want) {
+		t.Errorf("visited %v, want %v", got, want)
+	}
+
+	if lowerOpened != 0 {
+		t.Errorf("the lower layer is opened after the walk is stopped")
+	}
+}
+
+func TestFlattenLayers(t *testing.T) {
+	layers := []LayerOpener{
+		layerTar(t, nil, tarDir("etc/"), tarFile("etc/old", "x"), tarFile("etc/config.wh.json", "{}"), tarFile("bin/sh", "v1")),
+		layerTar(t, nil, tarFile("etc/.wh.old", ""), tarFile("bin/sh", "v2"), tarHardlink("bin/bash", "bin/sh")),
+	}
+
+	var got []string
+	err := WalkTar(FlattenLayers(layers), func(r io.Reader, header *tar.Header) error {
+		entry, err := describeEntry(r, header)
+		got = append(got, entry)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	slices.Sort(got)
+
+	if want := []string{"bin/bash->bin/sh", "bin/sh=v2", "etc", "etc/config.wh.json={}"}; !slices.Equal(got, want) {
+		t.Errorf("flattened %v, want %v", got, want)
+	}
+}
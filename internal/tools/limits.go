@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrLimitExceeded is returned when an extraction exceeds one of its limits
+var ErrLimitExceeded = errors.New("extraction limit exceeded")
+
+// Limits bound what an extraction may write, zero values mean no limit
+type Limits struct {
+	MaxTotalSize int64 // Total size of the written files
+	MaxFiles     int64 // Number of written files, directories and links
+	MaxFileSize  int64 // Size of a single file
+	MaxDepth     int   // Number of path components of an entry
+}
+
+// Limiter accounts the entries of an extraction against its limits
+type Limiter struct {
+	limits Limits
+	total  int64
+	files  int64
+}
+
+// NewLimiter creates a limiter, nil limits allow everything
+func NewLimiter(limits *Limits) *Limiter {
+	l := &Limiter{}
+	if limits != nil {
+		l.limits = *limits
+	}
+
+	return l
+}
+
+// Check accounts the entry before it is written and fails if it exceeds a limit,
+// so nothing above the limits reaches the disk
+func (l *Limiter) Check(header *tar.Header) error {
+	name := CleanPath(header.Name)
+
+	if l.limits.MaxDepth > 0 {
+		if depth := strings.Count(name, "/") + 1; depth > l.limits.MaxDepth {
+			return fmt.Errorf("%w: '%s' is %d levels deep, the maximum depth is %d",
+				ErrLimitExceeded, name, depth, l.limits.MaxDepth)
+		}
+	}
+
+	l.files++
+	if l.limits.MaxFiles > 0 && l.files > l.limits.MaxFiles {
+		return fmt.Errorf("%w: more than %d files", ErrLimitExceeded, l.limits.MaxFiles)
+	}
+
+	if header.Typeflag != tar.TypeReg {
+		return nil
+	}
+
+	if l.limits.MaxFileSize > 0 && header.Size > l.limits.MaxFileSize {
+		return fmt.Errorf("%w: '%s' is %s, the maximum file size is %s",
+			ErrLimitExceeded, name, FormatSize(header.Size), FormatSize(l.limits.MaxFileSize))
+	}
+
+	l.total += header.Size
+	if l.limits.MaxTotalSize > 0 && l.total > l.limits.MaxTotalSize {
+		return fmt.Errorf("%w: more than %s in total at '%s'",
+			ErrLimitExceeded, FormatSize(l.limits.MaxTotalSize), name)
+	}
+
+	return nil
+}
+
+// CreatedPaths records the paths an extraction creates, so a failed one can be removed
+// without touching what existed before
+type CreatedPaths struct {
+	paths []string
+}
+
+// Track records the topmost missing path among the target and its parents before
+// the target is written. Existing targets are overwritten and not recorded
+func (c *CreatedPaths) Track(target string) {
+	var missing string
+	for p := filepath.Clean(target); ; p = filepath.Dir(p) {
+		if _, err := os.Lstat(p); !errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+
+		missing = p
+		if filepath.Dir(p) == p {
+			break
+		}
+	}
+
+	if missing != "" {
+		c.paths = append(c.paths, missing)
+	}
+}
+
+// Remove deletes the created paths, the latest first
+func (c *CreatedPaths) Remove() error {
+	var errs []error
+	for _, p := range slices.Backward(c.paths) {
+		if err := os.RemoveAll(p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.paths = nil
+
+	return errors.Join(errs...)
+}
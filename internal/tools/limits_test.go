@@ -0,0 +1,188 @@
+package tools
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLimiterCheck(t *testing.T) {
+	file := func(name string, size int64) *tar.Header {
+		return &tar.Header{Name: name, Typeflag: tar.TypeReg, Size: size}
+	}
+	dir := func(name string) *tar.Header {
+		return &tar.Header{Name: name, Typeflag: tar.TypeDir}
+	}
+
+	tests := []struct {
+		name    string
+		limits  *Limits
+		headers []*tar.Header
+		// tripsAt is the index of the first rejected entry, -1 if all of them pass
+		tripsAt int
+	}{
+		{
+			name:    "no limits",
+			limits:  nil,
+			headers: []*tar.Header{file("a", 1<<40), dir("b/c/d/e/f")},
+			tripsAt: -1,
+		},
+		{
+			name:    "file size",
+			limits:  &Limits{MaxFileSize: 10},
+			headers: []*tar.Header{file("a", 10), dir("b"), file("c", 11)},
+			tripsAt: 2,
+		},
+		{
+			name:    "total size",
+			limits:  &Limits{MaxTotalSize: 10},
+			headers: []*tar.Header{file("a", 4), file("b", 6), dir("c"), file("d", 1)},
+			tripsAt: 3,
+		},
+		{
+			name:    "directories do not count to the total size",
+			limits:  &Limits{MaxTotalSize: 10},
+			headers: []*tar.Header{{Name: "a", Typeflag: tar.TypeDir, Size: 100}, file("b", 10)},
+			tripsAt: -1,
+		},
+		{
+			name:    "number of files",
+			limits:  &Limits{MaxFiles: 2},
+			headers: []*tar.Header{dir("a"), file("a/b", 1), {Name: "a/c", Typeflag: tar.TypeSymlink, Linkname: "b"}},
+			tripsAt: 2,
+		},
+		{
+			name:    "depth",
+			limits:  &Limits{MaxDepth: 2},
+			headers: []*tar.Header{dir("./a/"), file("/a/b", 1), file("a/b/c", 1)},
+			tripsAt: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewLimiter(tt.limits)
+
+			tripsAt := -1
+			for i, header := range tt.headers {
+				if err := limiter.Check(header); err != nil {
+					if !errors.Is(err, ErrLimitExceeded) {
+						t.Fatalf("unexpected error: %v", err)
+					}
+
+					tripsAt = i
+					break
+				}
+			}
+
+			if tripsAt != tt.tripsAt {
+				t.Errorf("the limit trips at entry %d, want %d", tripsAt, tt.tripsAt)
+			}
+		})
+	}
+}
+
+func TestCopyTarLimitsCleanup(t *testing.T) {
+	// The last entry is above the limit and would overwrite a file that exists before the extraction
+	headers := []*tar.Header{
+		{Name: "b", Typeflag: tar.TypeReg, Mode: 0o644, Size: 2},
+		{Name: "c", Typeflag: tar.TypeReg, Mode: 0o644, Size: 2},
+		{Name: "d/", Typeflag: tar.TypeDir, Mode: 0o755},
+		{Name: "etc/hosts", Typeflag: tar.TypeReg, Mode: 0o644, Size: 4},
+	}
+
+	tests := []struct {
+		name   string
+		limits *Limits
+	}{
+		{name: "file size", limits: &Limits{MaxFileSize: 3}},
+		{name: "total size", limits: &Limits{MaxTotalSize: 6}},
+		{name: "number of files", limits: &Limits{MaxFiles: 3}},
+		{name: "depth", limits: &Limits{MaxDepth: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := t.TempDir()
+			if err := os.Mkdir(filepath.Join(output, "etc"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{"keep", "etc/hosts"} {
+				if err := os.WriteFile(filepath.Join(output, name), []byte("original"), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := CopyTar(context.Background(), buildTarHeaders(t, headers...), output, &ExtractOptions{Limits: tt.limits})
+			if !errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("expected the limit to be exceeded, got %v", err)
+			}
+
+			// Only the paths created by the extraction are removed
+			entries, err := os.ReadDir(output)
+			if err != nil {
+				t.Fatalf("the output directory is removed: %v", err)
+			}
+
+			var names []string
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			if want := []string{"etc", "keep"}; !slices.Equal(names, want) {
+				t.Errorf("output contains %v, want %v", names, want)
+			}
+
+			for _, name := range []string{"keep", "etc/hosts"} {
+				content, err := os.ReadFile(filepath.Join(output, name))
+				if err != nil || string(content) != "original" {
+					t.Errorf("'%s' is changed: %q, %v", name, content, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCreatedPaths(t *testing.T) {
+	root := t.TempDir()
+	existing := filepath.Join(root, "existing")
+	if err := os.MkdirAll(existing, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(existing, "file"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var created CreatedPaths
+	// An existing target is overwritten and stays after the removal
+	created.Track(filepath.Join(existing, "file"))
+	// Only the topmost missing parent is recorded
+	deep := filepath.Join(existing, "new", "deep", "file")
+	created.Track(deep)
+
+	if want := []string{filepath.Join(existing, "new")}; !slices.Equal(created.paths, want) {
+		t.Errorf("tracked %v, want %v", created.paths, want)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(deep), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(deep, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := created.Remove(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(existing, "new")); !os.IsNotExist(err) {
+		t.Errorf("the created directory is not removed: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(existing, "file")); err != nil {
+		t.Errorf("the existing file is removed: %v", err)
+	}
+}
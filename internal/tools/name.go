@@ -1,7 +1,9 @@
 package tools
 
 import (
+	"fmt"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
@@ -24,3 +26,75 @@ func MatchName(filePath, artifact string) bool {
 
 	return false
 }
+
+// Matcher selects artifacts by name, glob pattern or regular expression
+type Matcher struct {
+	Pattern string
+	name    string
+	re      *regexp.Regexp
+}
+
+// NewMatcher creates an artifact matcher. With regex the pattern is a regular expression
+// searched in the path, otherwise patterns with glob meta characters are globs ('usr/lib/**/*.so*')
+// and other names match as MatchName does. Leading slashes are ignored
+func NewMatcher(pattern string, regex bool) (*Matcher, error) {
+	m := &Matcher{Pattern: pattern}
+
+	var err error
+	switch {
+	case regex:
+		if m.re, err = regexp.Compile(pattern); err != nil {
+			return nil, fmt.Errorf("invalid regular expression '%s': %w", pattern, err)
+		}
+	case IsGlob(pattern):
+		if m.re, err = CompileGlob(pattern); err != nil {
+			return nil, err
+		}
+	default:
+		m.name = strings.TrimPrefix(pattern, "/")
+	}
+
+	return m, nil
+}
+
+// NewMatchers creates matchers for all patterns
+func NewMatchers(patterns []string, regex bool) ([]*Matcher, error) {
+	matchers := make([]*Matcher, 0, len(patterns))
+	for _, pattern := range patterns {
+		m, err := NewMatcher(pattern, regex)
+		if err != nil {
+			return nil, err
+		}
+
+		matchers = append(matchers, m)
+	}
+
+	return matchers, nil
+}
+
+// Match checks if the path matches
+func (m *Matcher) Match(name string) bool {
+	name = CleanPath(name)
+	if m.re != nil {
+		return m.re.MatchString(name)
+	}
+
+	return MatchName(name, m.name)
+}
+
+// IsPattern checks if the matcher is a glob or a regular expression,
+// which may select any number of unrelated paths
+func (m *Matcher) IsPattern() bool {
+	return m.re != nil
+}
+
+// MatchAny checks if the path matches any of the matchers
+func MatchAny(matchers []*Matcher, name string) bool {
+	for _, m := range matchers {
+		if m.Match(name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,316 @@
+package tools
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// OSVEntry is a vulnerability in the OSV format, see https://ossf.github.io/osv-schema/
+type OSVEntry struct {
+	ID               string         `json:"id"`
+	Aliases          []string       `json:"aliases,omitempty"`
+	Summary          string         `json:"summary,omitempty"`
+	Details          string         `json:"details,omitempty"`
+	Withdrawn        string         `json:"withdrawn,omitempty"`
+	Severity         []OSVSeverity  `json:"severity,omitempty"`
+	Affected         []OSVAffected  `json:"affected,omitempty"`
+	DatabaseSpecific map[string]any `json:"database_specific,omitempty"`
+}
+
+// OSVSeverity is a severity score of the vulnerability (e.g. CVSS vector)
+type OSVSeverity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+// OSVAffected describes the affected versions of a package
+type OSVAffected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+	} `json:"package"`
+	Severity          []OSVSeverity  `json:"severity,omitempty"`
+	Ranges            []OSVRange     `json:"ranges,omitempty"`
+	Versions          []string       `json:"versions,omitempty"`
+	EcosystemSpecific map[string]any `json:"ecosystem_specific,omitempty"`
+	DatabaseSpecific  map[string]any `json:"database_specific,omitempty"`
+}
+
+// OSVRange is a range of affected versions described by events
+type OSVRange struct {
+	Type   string     `json:"type"`
+	Events []OSVEvent `json:"events"`
+}
+
+// OSVEvent is a version where the package became affected or was fixed
+type OSVEvent struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// Severity levels ordered from the lowest
+const (
+	SeverityUnknown  = "unknown"
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// SeverityRank returns the rank of the severity level, unknown levels have the lowest rank
+func SeverityRank(severity string) int {
+	switch strings.ToLower(severity) {
+	case SeverityLow:
+		return 1
+	case SeverityMedium, "moderate":
+		return 2
+	case SeverityHigh, "important":
+		return 3
+	case SeverityCritical:
+		return 4
+	default:
+		return 0
+	}
+}
+
+// LoadOSV loads OSV entries from JSON files and zip archives (as published by osv.dev) in the directory.
+// Only entries affecting packages accepted by keep are returned
+func LoadOSV(dir string, keep func(ecosystem, name string) bool) ([]*OSVEntry, error) {
+	var entries []*OSVEntry
+
+	add := func(r io.Reader, name string) error {
+		entry := new(OSVEntry)
+		if err := json.NewDecoder(r).Decode(entry); err != nil {
+			return fmt.Errorf("parse the OSV entry '%s': %w", name, err)
+		}
+
+		if entry.Withdrawn != "" {
+			return nil
+		}
+
+		for _, affected := range entry.Affected {
+			if keep(affected.Package.Ecosystem, affected.Package.Name) {
+				entries = append(entries, entry)
+				return nil
+			}
+		}
+
+		return nil
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return nil
+		case strings.HasSuffix(path, ".json"):
+			file, err := os.Open(path)
+			if err != nil {
+				return fmt.Errorf("open '%s': %w", path, err)
+			}
+			defer file.Close()
+
+			return add(file, path)
+		case strings.HasSuffix(path, ".zip"):
+			return loadOSVZip(path, add)
+		default:
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load the OSV database '%s': %w", dir, err)
+	}
+
+	return entries, nil
+}
+
+// loadOSVZip loads every JSON file in the zip archive
+func loadOSVZip(path string, add func(r io.Reader, name string) error) error {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("open '%s': %w", path, err)
+	}
+	defer archive.Close()
+
+	for _, file := range archive.File {
+		if !strings.HasSuffix(file.Name, ".json") {
+			continue
+		}
+
+		r, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("open '%s' in '%s': %w", file.Name, path, err)
+		}
+
+		err = add(r, path+":"+file.Name)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MatchEcosystem checks if the OSV ecosystem applies to the package ecosystem.
+// 'Debian' matches all Debian releases, 'Debian:12' matches only 'Debian:12'
+func MatchEcosystem(osvEcosystem, ecosystem string) bool {
+	if osvEcosystem == ecosystem || strings.HasPrefix(osvEcosystem, ecosystem+":") {
+		return true
+	}
+
+	return !strings.Contains(osvEcosystem, ":") && osvEcosystem == EcosystemBase(ecosystem)
+}
+
+// Affects checks if the version is affected, it also returns the first fixed version if known
+func (a *OSVAffected) Affects(ecosystem, version string) (bool, string) {
+	for _, v := range a.Versions {
+		if CompareVersions(ecosystem, v, version) == 0 {
+			return true, ""
+		}
+	}
+
+	for _, r := range a.Ranges {
+		if r.Type != "SEMVER" && r.Type != "ECOSYSTEM" {
+			continue
+		}
+
+		if affected, fixed := r.affects(ecosystem, version); affected {
+			return true, fixed
+		}
+	}
+
+	return false, ""
+}
+
+// osvPoint is a single range event with its version
+type osvPoint struct {
+	version string
+	kind    string
+}
+
+// affects evaluates the range events in version order
+func (r *OSVRange) affects(ecosystem, version string) (bool, string) {
+	var points []osvPoint
+	for _, event := range r.Events {
+		switch {
+		case event.Introduced != "":
+			points = append(points, osvPoint{version: event.Introduced, kind: "introduced"})
+		case event.Fixed != "":
+			points = append(points, osvPoint{version: event.Fixed, kind: "fixed"})
+		case event.LastAffected != "":
+			points = append(points, osvPoint{version: event.LastAffected, kind: "last_affected"})
+		}
+	}
+
+	compare := func(a, b string) int {
+		switch {
+		case a == "0" && b == "0":
+			return 0
+		case a == "0":
+			return -1
+		case b == "0":
+			return 1
+		default:
+			return CompareVersions(ecosystem, a, b)
+		}
+	}
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return compare(points[i].version, points[j].version) < 0
+	})
+
+	affected := false
+	for i, point := range points {
+		switch point.kind {
+		case "introduced":
+			if compare(version, point.version) >= 0 {
+				affected = true
+			}
+		case "fixed":
+			if compare(version, point.version) >= 0 {
+				affected = false
+			}
+		case "last_affected":
+			if compare(version, point.version) > 0 {
+				affected = false
+			}
+		}
+
+		if affected && (i == len(points)-1 || compare(version, points[i+1].version) < 0) {
+			return true, nextFixed(points[i+1:])
+		}
+	}
+
+	return false, ""
+}
+
+// nextFixed returns the first fixed version among the points
+func nextFixed(points []osvPoint) string {
+	for _, point := range points {
+		if point.kind == "fixed" {
+			return point.version
+		}
+	}
+
+	return ""
+}
+
+// SeverityOf returns the severity level of the vulnerability for the affected package
+func (e *OSVEntry) SeverityOf(affected *OSVAffected) string {
+	for _, specific := range []map[string]any{affected.EcosystemSpecific, affected.DatabaseSpecific, e.DatabaseSpecific} {
+		if severity, ok := specific["severity"].(string); ok && SeverityRank(severity) > 0 {
+			return normalizeSeverity(severity)
+		}
+	}
+
+	for _, severities := range [][]OSVSeverity{affected.Severity, e.Severity} {
+		for _, severity := range severities {
+			switch severity.Type {
+			case "CVSS_V3":
+				if score, err := CVSS3Score(severity.Score); err == nil {
+					return cvssSeverity(score)
+				}
+			case "Ubuntu":
+				if SeverityRank(severity.Score) > 0 {
+					return normalizeSeverity(severity.Score)
+				}
+			}
+		}
+	}
+
+	return SeverityUnknown
+}
+
+// normalizeSeverity maps severity names used by different databases to the common levels
+func normalizeSeverity(severity string) string {
+	return []string{SeverityUnknown, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}[SeverityRank(severity)]
+}
+
+// cvssSeverity maps the CVSS score to the qualitative severity rating
+func cvssSeverity(score float64) string {
+	switch {
+	case score >= 9:
+		return SeverityCritical
+	case score >= 7:
+		return SeverityHigh
+	case score >= 4:
+		return SeverityMedium
+	case score > 0:
+		return SeverityLow
+	default:
+		return SeverityUnknown
+	}
+}
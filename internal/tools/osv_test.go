@@ -0,0 +1,168 @@
+package tools
+
+import "testing"
+
+func TestOSVAffectedAffects(t *testing.T) {
+	introduced := func(v string) OSVEvent { return OSVEvent{Introduced: v} }
+	fixed := func(v string) OSVEvent { return OSVEvent{Fixed: v} }
+	lastAffected := func(v string) OSVEvent { return OSVEvent{LastAffected: v} }
+
+	tests := []struct {
+		name      string
+		ecosystem string
+		affected  OSVAffected
+		version   string
+		want      bool
+		wantFixed string
+	}{
+		{
+			name:      "debian before the fix",
+			ecosystem: "Debian:12",
+			affected:  OSVAffected{Ranges: []OSVRange{{Type: "ECOSYSTEM", Events: []OSVEvent{introduced("0"), fixed("1.2-3")}}}},
+			version:   "1.2-2",
+			want:      true,
+			wantFixed: "1.2-3",
+		},
+		{
+			name:      "debian fixed version",
+			ecosystem: "Debian:12",
+			affected:  OSVAffected{Ranges: []OSVRange{{Type: "ECOSYSTEM", Events: []OSVEvent{introduced("0"), fixed("1.2-3")}}}},
+			version:   "1.2-3",
+		},
+		{
+			name:      "debian epoch above the fix",
+			ecosystem: "Debian:12",
+			affected:  OSVAffected{Ranges: []OSVRange{{Type: "ECOSYSTEM", Events: []OSVEvent{introduced("0"), fixed("1.2-3")}}}},
+			version:   "1:0.9-1",
+		},
+		{
+			name:      "debian tilde before a pre-release fix",
+			ecosystem: "Debian:12",
+			affected:  OSVAffected{Ranges: []OSVRange{{Type: "ECOSYSTEM", Events: []OSVEvent{introduced("0"), fixed("2.0~rc1-1")}}}},
+			version:   "2.0~beta1-1",
+			want:      true,
+			wantFixed: "2.0~rc1-1",
+		},
+		{
+			name:      "debian release after a pre-release fix",
+			ecosystem: "Debian:12",
+			affected:  OSVAffected{Ranges: []OSVRange{{Type: "ECOSYSTEM", Events: []OSVEvent{introduced("0"), fixed("2.0~rc1-1")}}}},
+			version:   "2.0-1",
+		},
+		{
+			name:      "alpine release candidate before the fix",
+			ecosystem: "Alpine:v3.19",
+			affected:  OSVAffected{Ranges: []OSVRange{{Type: "ECOSYSTEM", Events: []OSVEvent{introduced("0"), fixed("3.0.8-r0")}}}},
+			version:   "3.0.8_rc1-r0",
+			want:      true,
+			wantFixed: "3.0.8-r0",
+		},
+		{
+			name:      "alpine revision before the fix",
+			ecosystem: "Alpine:v3.19",
+			affected:  OSVAffected{Ranges: []OSVRange{{Type: "ECOSYSTEM", Events: []OSVEvent{introduced("0"), fixed("3.0.8-r1")}}}},
+			version:   "3.0.8-r0",
+			want:      true,
+			wantFixed: "3.0.8-r1",
+		},
+		{
+			name:      "alpine fixed revision",
+			ecosystem: "Alpine:v3.19",
+			affected:  OSVAffected{Ranges: []OSVRange{{Type: "ECOSYSTEM", Events: []OSVEvent{introduced("0"), fixed("3.0.8-r1")}}}},
+			version:   "3.0.8-r1",
+		},
+		{
+			name:      "before introduced",
+			ecosystem: "Go",
+			affected:  OSVAffected{Ranges: []OSVRange{{Type: "SEMVER", Events: []OSVEvent{introduced("1.0.0"), fixed("1.5.0")}}}},
+			version:   "0.9.0",
+		},
+		{
+			name:      "introduced version",
+			ecosystem: "Go",
+			affected:  OSVAffected{Ranges: []OSVRange{{Type: "SEMVER", Events: []OSVEvent{introduced("1.0.0"), fixed("1.5.0")}}}},
+			version:   "1.0.0",
+			want:      true,
+			wantFixed: "1.5.0",
+		},
+		{
+			name:      "between two affected ranges",
+			ecosystem: "Go",
+			affected: OSVAffected{Ranges: []OSVRange{{Type: "SEMVER", Events: []OSVEvent{
+				introduced("1.0.0"), fixed("1.5.0"), introduced("2.0.0"), fixed("2.3.0"),
+			}}}},
+			version: "1.9.0",
+		},
+		{
+			name:      "second affected range",
+			ecosystem: "Go",
+			affected: OSVAffected{Ranges: []OSVRange{{Type: "SEMVER", Events: []OSVEvent{
+				introduced("2.0.0"), fixed("2.3.0"), introduced("1.0.0"), fixed("1.5.0"),
+			}}}},
+			version:   "2.1.0",
+			want:      true,
+			wantFixed: "2.3.0",
+		},
+		{
+			name:      "last affected version",
+			ecosystem: "Go",
+			affected:  OSVAffected{Ranges: []OSVRange{{Type: "SEMVER", Events: []OSVEvent{introduced("0"), lastAffected("1.2.3")}}}},
+			version:   "1.2.3",
+			want:      true,
+		},
+		{
+			name:      "after the last affected version",
+			ecosystem: "Go",
+			affected:  OSVAffected{Ranges: []OSVRange{{Type: "SEMVER", Events: []OSVEvent{introduced("0"), lastAffected("1.2.3")}}}},
+			version:   "1.2.4",
+		},
+		{
+			name:      "introduced without a fix",
+			ecosystem: "Go",
+			affected:  OSVAffected{Ranges: []OSVRange{{Type: "SEMVER", Events: []OSVEvent{introduced("2.0.0")}}}},
+			version:   "3.0.0",
+			want:      true,
+		},
+		{
+			name:      "listed version",
+			ecosystem: "Debian:12",
+			affected:  OSVAffected{Versions: []string{"1.0-1", "1.0-2"}},
+			version:   "1.0-2",
+			want:      true,
+		},
+		{
+			name:      "git ranges are ignored",
+			ecosystem: "Go",
+			affected:  OSVAffected{Ranges: []OSVRange{{Type: "GIT", Events: []OSVEvent{introduced("0")}}}},
+			version:   "1.0.0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, gotFixed := tt.affected.Affects(tt.ecosystem, tt.version)
+			if got != tt.want || gotFixed != tt.wantFixed {
+				t.Errorf("Affects(%q, %q) = %v, %q, want %v, %q", tt.ecosystem, tt.version, got, gotFixed, tt.want, tt.wantFixed)
+			}
+		})
+	}
+}
+
+func TestMatchEcosystem(t *testing.T) {
+	tests := []struct {
+		osv, ecosystem string
+		want           bool
+	}{
+		{osv: "Debian", ecosystem: "Debian:12", want: true},
+		{osv: "Debian:12", ecosystem: "Debian:12", want: true},
+		{osv: "Debian:11", ecosystem: "Debian:12", want: false},
+		{osv: "Alpine:v3.19", ecosystem: "Alpine", want: true},
+		{osv: "Ubuntu", ecosystem: "Debian:12", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchEcosystem(tt.osv, tt.ecosystem); got != tt.want {
+			t.Errorf("MatchEcosystem(%q, %q) = %v, want %v", tt.osv, tt.ecosystem, got, tt.want)
+		}
+	}
+}
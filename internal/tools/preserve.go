@@ -0,0 +1,277 @@
+package tools
+
+import (
+	"archive/tar"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// paxXattrPrefix is the PAX record prefix of extended attributes
+const paxXattrPrefix = "SCHILY.xattr."
+
+// Preserve lists the metadata restored on extraction in addition to the permission bits
+type Preserve struct {
+	Owner  bool
+	Time   bool
+	Xattrs bool
+}
+
+// ParsePreserve parses metadata names: owner, time, xattrs or all
+func ParsePreserve(values []string) (Preserve, error) {
+	var p Preserve
+	for _, value := range values {
+		switch strings.TrimSpace(value) {
+		case "owner":
+			p.Owner = true
+		case "time":
+			p.Time = true
+		case "xattrs":
+			p.Xattrs = true
+		case "all":
+			p = Preserve{Owner: true, Time: true, Xattrs: true}
+		default:
+			return p, fmt.Errorf("unknown metadata '%s', expected owner, time, xattrs or all", value)
+		}
+	}
+
+	return p, nil
+}
+
+// IDMap maps user or group ids of the image to ids on the host
+type IDMap struct {
+	ids map[int]int
+	all *int
+}
+
+// ParseIDMap parses 'ID' values mapping every id and 'FROM:TO' values mapping a single id
+func ParseIDMap(values []string) (*IDMap, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	m := &IDMap{ids: make(map[int]int)}
+	for _, value := range values {
+		from, to, ok := strings.Cut(value, ":")
+		if !ok {
+			id, err := parseID(value)
+			if err != nil {
+				return nil, err
+			}
+
+			m.all = &id
+			continue
+		}
+
+		fromID, err := parseID(from)
+		if err != nil {
+			return nil, err
+		}
+
+		toID, err := parseID(to)
+		if err != nil {
+			return nil, err
+		}
+
+		m.ids[fromID] = toID
+	}
+
+	return m, nil
+}
+
+func parseID(s string) (int, error) {
+	id, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid id '%s', expected a non-negative number", s)
+	}
+
+	return id, nil
+}
+
+// Map returns the host id of the image id, ids without a mapping are kept
+func (m *IDMap) Map(id int) int {
+	if m == nil {
+		return id
+	}
+
+	if mapped, ok := m.ids[id]; ok {
+		return mapped
+	}
+
+	if m.all != nil {
+		return *m.all
+	}
+
+	return id
+}
+
+// ExtractOptions controls how CopyTar writes the image to disk
+type ExtractOptions struct {
+	// Preserve restores owners, timestamps and extended attributes from the image
+	Preserve Preserve
+	// UIDMap and GIDMap change the owners restored with Preserve.Owner
+	UIDMap *IDMap
+	GIDMap *IDMap
+	// Devices creates device nodes and FIFOs, the ones that need privileges are
+	// recorded in the DevicesManifest script instead
+	Devices         bool
+	DevicesManifest string
+	// Include and Exclude select the extracted entries, all of them by default
+	Include []*Matcher
+	Exclude []*Matcher
+	// Limits abort the extraction when it writes too much
+	Limits *Limits
+	// Strict rejects entries and links leaving the output directory instead of skipping them,
+	// including symlinks with absolute targets
+	Strict bool
+}
+
+// filtered checks if only a part of the image is extracted
+func (o *ExtractOptions) filtered() bool {
+	return len(o.Include) > 0 || len(o.Exclude) > 0
+}
+
+// selected checks if the image path passes the include and exclude filters
+func (o *ExtractOptions) selected(name string) bool {
+	if len(o.Include) > 0 && !MatchAny(o.Include, name) {
+		return false
+	}
+
+	return !MatchAny(o.Exclude, name)
+}
+
+// Xattrs returns the extended attributes stored in the tar header
+func Xattrs(header *tar.Header) map[string]string {
+	xattrs := make(map[string]string)
+	for key, value := range header.PAXRecords {
+		if name, ok := strings.CutPrefix(key, paxXattrPrefix); ok {
+			xattrs[name] = value
+		}
+	}
+
+	return xattrs
+}
+
+// dirTime is a directory modification time restored after its contents are written
+type dirTime struct {
+	path   string
+	header *tar.Header
+}
+
+// metadataRestorer applies preserved metadata to extracted entries.
+// Failures, e.g. chown without privileges, are collected as warnings instead of aborting the extraction
+type metadataRestorer struct {
+	opts   *ExtractOptions
+	dirs   []dirTime
+	failed map[string]int
+	errs   map[string]error
+}
+
+func newMetadataRestorer(opts *ExtractOptions) *metadataRestorer {
+	if opts == nil {
+		opts = &ExtractOptions{}
+	}
+
+	return &metadataRestorer{
+		opts:   opts,
+		failed: make(map[string]int),
+		errs:   make(map[string]error),
+	}
+}
+
+// restore applies the metadata of the header to the extracted entry.
+// The owner goes first since chown clears setuid bits and file capabilities
+func (m *metadataRestorer) restore(header *tar.Header, target string) {
+	// Hard links share the metadata of their target
+	if header.Typeflag == tar.TypeLink {
+		return
+	}
+
+	symlink := header.Typeflag == tar.TypeSymlink
+
+	if m.opts.Preserve.Owner {
+		uid := m.opts.UIDMap.Map(header.Uid)
+		gid := m.opts.GIDMap.Map(header.Gid)
+		if err := os.Lchown(target, uid, gid); err != nil {
+			m.fail("owner", err)
+		} else if !symlink {
+			if err = os.Chmod(target, fileMode(header)); err != nil {
+				m.fail("mode", err)
+			}
+		}
+	}
+
+	if m.opts.Preserve.Xattrs && !symlink {
+		xattrs := Xattrs(header)
+		for _, name := range slices.Sorted(maps.Keys(xattrs)) {
+			if err := setXattr(target, name, []byte(xattrs[name])); err != nil {
+				m.fail("xattrs", fmt.Errorf("set %s of %s: %w", name, target, err))
+			}
+		}
+	}
+
+	if m.opts.Preserve.Time {
+		if header.Typeflag == tar.TypeDir {
+			// Writing the directory contents changes its modification time
+			m.dirs = append(m.dirs, dirTime{path: target, header: header})
+			return
+		}
+
+		if err := setTimes(target, header, symlink); err != nil {
+			m.fail("time", err)
+		}
+	}
+}
+
+// finish restores directory times, deepest first, and returns the warnings
+func (m *metadataRestorer) finish() []string {
+	for _, dir := range slices.Backward(m.dirs) {
+		if err := setTimes(dir.path, dir.header, false); err != nil {
+			m.fail("time", err)
+		}
+	}
+
+	warnings := make([]string, 0, len(m.failed))
+	for _, kind := range slices.Sorted(maps.Keys(m.failed)) {
+		warnings = append(warnings, fmt.Sprintf("Could not preserve %s of %d entries: %v", kind, m.failed[kind], m.errs[kind]))
+	}
+
+	return warnings
+}
+
+func (m *metadataRestorer) fail(kind string, err error) {
+	if m.failed[kind] == 0 {
+		m.errs[kind] = err
+	}
+
+	m.failed[kind]++
+}
+
+// fileMode converts the tar mode to a file mode keeping setuid, setgid and sticky bits
+func fileMode(header *tar.Header) os.FileMode {
+	mode := os.FileMode(header.Mode).Perm()
+	if header.Mode&04000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if header.Mode&02000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if header.Mode&01000 != 0 {
+		mode |= os.ModeSticky
+	}
+
+	return mode
+}
+
+// accessTime returns the access time of the entry, the modification time if not recorded
+func accessTime(header *tar.Header) time.Time {
+	if header.AccessTime.IsZero() {
+		return header.ModTime
+	}
+
+	return header.AccessTime
+}
@@ -0,0 +1,25 @@
+//go:build !unix
+
+package tools
+
+import (
+	"archive/tar"
+	"errors"
+	"os"
+)
+
+var errNotSupported = errors.New("not supported on this platform")
+
+// setXattr sets an extended attribute of the file, e.g. security.capability
+func setXattr(_, _ string, _ []byte) error {
+	return errNotSupported
+}
+
+// setTimes restores access and modification times, symlinks are not supported
+func setTimes(path string, header *tar.Header, symlink bool) error {
+	if symlink {
+		return errNotSupported
+	}
+
+	return os.Chtimes(path, accessTime(header), header.ModTime)
+}
@@ -0,0 +1,181 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildTarHeaders writes the headers as they are, regular files get Size bytes of content
+func buildTarHeaders(t *testing.T, headers ...*tar.Header) io.ReadCloser {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, header := range headers {
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("write header '%s': %v", header.Name, err)
+		}
+
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tw.Write(bytes.Repeat([]byte("x"), int(header.Size))); err != nil {
+				t.Fatalf("write '%s': %v", header.Name, err)
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+
+	return io.NopCloser(&buf)
+}
+
+func TestParsePreserve(t *testing.T) {
+	tests := []struct {
+		values  []string
+		want    Preserve
+		wantErr bool
+	}{
+		{values: nil, want: Preserve{}},
+		{values: []string{"owner"}, want: Preserve{Owner: true}},
+		{values: []string{"time", " xattrs"}, want: Preserve{Time: true, Xattrs: true}},
+		{values: []string{"all"}, want: Preserve{Owner: true, Time: true, Xattrs: true}},
+		{values: []string{"owner", "all"}, want: Preserve{Owner: true, Time: true, Xattrs: true}},
+		{values: []string{"mode"}, wantErr: true},
+		{values: []string{""}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParsePreserve(tt.values)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePreserve(%q) error = %v, want error %v", tt.values, err, tt.wantErr)
+			continue
+		}
+
+		if err == nil && got != tt.want {
+			t.Errorf("ParsePreserve(%q) = %+v, want %+v", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestParseIDMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		wantErr bool
+		// mapped lists image ids and the host ids they are mapped to
+		mapped map[int]int
+	}{
+		{
+			name:   "no mapping",
+			values: nil,
+			mapped: map[int]int{0: 0, 1000: 1000},
+		},
+		{
+			name:   "every id",
+			values: []string{"1000"},
+			mapped: map[int]int{0: 1000, 33: 1000},
+		},
+		{
+			name:   "single ids",
+			values: []string{"0:1000", "33:1001"},
+			mapped: map[int]int{0: 1000, 33: 1001, 50: 50},
+		},
+		{
+			name:   "single ids take precedence over every id",
+			values: []string{"0:1000", "2000"},
+			mapped: map[int]int{0: 1000, 33: 2000},
+		},
+		{
+			name:   "precedence does not depend on the order",
+			values: []string{"2000", "0:1000"},
+			mapped: map[int]int{0: 1000, 33: 2000},
+		},
+		{
+			name:   "spaces around ids",
+			values: []string{" 0 : 1000 "},
+			mapped: map[int]int{0: 1000},
+		},
+		{name: "negative id", values: []string{"-1"}, wantErr: true},
+		{name: "not a number", values: []string{"root:1000"}, wantErr: true},
+		{name: "missing target", values: []string{"0:"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseIDMap(tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseIDMap(%q) error = %v, want error %v", tt.values, err, tt.wantErr)
+			}
+
+			for id, want := range tt.mapped {
+				if got := m.Map(id); got != want {
+					t.Errorf("Map(%d) = %d, want %d", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCopyTarPreserveTime(t *testing.T) {
+	at := func(sec int64) time.Time { return time.Unix(1_700_000_000+sec, 0) }
+
+	// The directories come before their contents, so writing the contents changes their times
+	headers := []*tar.Header{
+		{Name: "d/", Typeflag: tar.TypeDir, Mode: 0o755, ModTime: at(1)},
+		{Name: "d/sub/", Typeflag: tar.TypeDir, Mode: 0o755, ModTime: at(2)},
+		{Name: "d/sub/file", Typeflag: tar.TypeReg, Mode: 0o644, Size: 4, ModTime: at(3)},
+		{Name: "d/file", Typeflag: tar.TypeReg, Mode: 0o644, Size: 4, ModTime: at(4), AccessTime: at(5), Format: tar.FormatPAX},
+		{Name: "d/link", Typeflag: tar.TypeSymlink, Linkname: "file", ModTime: at(6)},
+	}
+
+	output := t.TempDir()
+	opts := &ExtractOptions{Preserve: Preserve{Time: true}}
+	res, err := CopyTar(context.Background(), buildTarHeaders(t, headers...), output, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(res.Warnings) > 0 {
+		t.Errorf("unexpected warnings: %v", res.Warnings)
+	}
+
+	for _, header := range headers {
+		stat, err := os.Lstat(filepath.Join(output, filepath.FromSlash(strings.TrimSuffix(header.Name, "/"))))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !stat.ModTime().Equal(header.ModTime) {
+			t.Errorf("'%s' is modified at %v, want %v", header.Name, stat.ModTime(), header.ModTime)
+		}
+	}
+}
+
+func TestCopyTarWithoutPreserveTime(t *testing.T) {
+	old := time.Unix(1_000_000_000, 0)
+	headers := []*tar.Header{
+		{Name: "file", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1, ModTime: old},
+	}
+
+	output := t.TempDir()
+	if _, err := CopyTar(context.Background(), buildTarHeaders(t, headers...), output, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stat, err := os.Stat(filepath.Join(output, "file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stat.ModTime().Equal(old) {
+		t.Errorf("the time of 'file' is restored without Preserve.Time")
+	}
+}
@@ -0,0 +1,29 @@
+//go:build unix
+
+package tools
+
+import (
+	"archive/tar"
+
+	"golang.org/x/sys/unix"
+)
+
+// setXattr sets an extended attribute of the file, e.g. security.capability
+func setXattr(path, name string, value []byte) error {
+	return unix.Lsetxattr(path, name, value, 0)
+}
+
+// setTimes restores access and modification times, not following symlinks
+func setTimes(path string, header *tar.Header, symlink bool) error {
+	flags := 0
+	if symlink {
+		flags = unix.AT_SYMLINK_NOFOLLOW
+	}
+
+	times := []unix.Timespec{
+		unix.NsecToTimespec(accessTime(header).UnixNano()),
+		unix.NsecToTimespec(header.ModTime.UnixNano()),
+	}
+
+	return unix.UtimesNanoAt(unix.AT_FDCWD, path, times, flags)
+}
@@ -0,0 +1,113 @@
+package tools
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsafePath is returned in strict mode for entries and links leaving the extraction root
+var ErrUnsafePath = errors.New("unsafe path")
+
+// SecureJoin joins the image path to the root, resolving every parent component on disk
+// as if the root were the filesystem root: symlinks are followed relative to it and ".."
+// never leaves it. The last component is not followed, since it is the entry being written
+func SecureJoin(root, name string) (string, error) {
+	dir, base := path.Split(CleanPath(name))
+	if base == "" {
+		return filepath.Clean(root), nil
+	}
+
+	resolved, err := resolveInRoot(root, dir)
+	if err != nil {
+		return "", fmt.Errorf("resolve '%s': %w", name, err)
+	}
+
+	return filepath.Join(root, filepath.FromSlash(resolved), base), nil
+}
+
+// resolveInRoot resolves the path component by component, following symlinks inside the root.
+// Components that do not exist yet are kept as they are
+func resolveInRoot(root, p string) (string, error) {
+	var current string
+	var links int
+
+	remaining := p
+	for remaining != "" {
+		var part string
+		part, remaining, _ = strings.Cut(remaining, "/")
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			current = CleanPath(path.Dir(current))
+			continue
+		}
+
+		next := path.Join(current, part)
+		full := filepath.Join(root, filepath.FromSlash(next))
+
+		stat, err := os.Lstat(full)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		if err != nil || stat.Mode()&os.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+
+		if links++; links > maxSymlinks {
+			return "", errors.New("too many levels of symbolic links")
+		}
+
+		target, err := os.Readlink(full)
+		if err != nil {
+			return "", err
+		}
+
+		// Absolute targets start over from the root, relative ones from the link's directory
+		if path.IsAbs(target) {
+			current = ""
+		}
+		remaining = target + "/" + remaining
+	}
+
+	return current, nil
+}
+
+// CheckLink rejects the links strict extraction refuses: symlinks with absolute targets
+// or targets leaving the root, and hardlinks whose target is absolute, the root or outside of it
+func CheckLink(header *tar.Header) error {
+	switch header.Typeflag {
+	case tar.TypeSymlink:
+		if IsUnsafeLink(header.Name, header.Linkname) {
+			return fmt.Errorf("symlink '%s' -> '%s' leaves the target directory: %w", header.Name, header.Linkname, ErrUnsafePath)
+		}
+	case tar.TypeLink:
+		// Hardlink targets are image paths of files: '..' leaves the root, while absolute
+		// targets and the root itself are never valid
+		if target := path.Clean(header.Linkname); path.IsAbs(target) || target == "." || target == ".." || strings.HasPrefix(target, "../") {
+			return fmt.Errorf("hardlink '%s' -> '%s' leaves the target directory: %w", header.Name, header.Linkname, ErrUnsafePath)
+		}
+	}
+
+	return nil
+}
+
+// IsUnsafeLink checks if the symlink target is absolute or leaves the root
+// when resolved from the directory of the link
+func IsUnsafeLink(name, linkname string) bool {
+	if path.IsAbs(linkname) {
+		return true
+	}
+
+	joined := path.Join(path.Dir(CleanPath(name)), linkname)
+	return joined == ".." || strings.HasPrefix(joined, "../")
+}
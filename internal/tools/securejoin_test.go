@@ -0,0 +1,443 @@
+package tools
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type tarEntry struct {
+	name string
+	typ  byte
+	link string
+	body string
+}
+
+func tarFile(name, body string) tarEntry { return tarEntry{name: name, typ: tar.TypeReg, body: body} }
+func tarDir(name string) tarEntry        { return tarEntry{name: name, typ: tar.TypeDir} }
+func tarSymlink(name, link string) tarEntry {
+	return tarEntry{name: name, typ: tar.TypeSymlink, link: link}
+}
+func tarHardlink(name, link string) tarEntry {
+	return tarEntry{name: name, typ: tar.TypeLink, link: link}
+}
+
+// buildTar writes the entries as they are, without the cleaning of a well-behaved archiver
+func buildTar(t *testing.T, entries ...tarEntry) io.ReadCloser {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Typeflag: e.typ, Linkname: e.link, Mode: 0o755, Size: int64(len(e.body))}
+		if e.typ == tar.TypeReg {
+			header.Mode = 0o644
+		} else {
+			header.Size = 0
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("write header '%s': %v", e.name, err)
+		}
+
+		if _, err := tw.Write([]byte(e.body)); err != nil && e.typ == tar.TypeReg {
+			t.Fatalf("write '%s': %v", e.name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+
+	return io.NopCloser(&buf)
+}
+
+// setupRoots creates the output directory and a directory outside of it with a secret file
+func setupRoots(t *testing.T) (string, string) {
+	t.Helper()
+
+	base := t.TempDir()
+	output := filepath.Join(base, "out")
+	outside := filepath.Join(base, "outside")
+
+	for _, d := range []string{output, outside} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(outside, "secret"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return output, outside
+}
+
+func TestCopyTarMaliciousArchives(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries func(outside string) []tarEntry
+		strict  bool
+		// withDeps writes the entries at their image paths the way cp --with-deps does
+		withDeps bool
+		wantErr  error
+		// inside lists paths relative to the output expected to exist after the extraction,
+		// $OUTSIDE is replaced with the path of the directory outside
+		inside []string
+	}{
+		{
+			name: "absolute symlink as parent",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{tarSymlink("a", outside), tarFile("a/evil", "x")}
+			},
+			inside: []string{"a", "$OUTSIDE/evil"},
+		},
+		{
+			name: "relative symlink escaping as parent",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarSymlink("a", "../outside"), tarFile("a/evil", "x")}
+			},
+			inside: []string{"outside/evil"},
+		},
+		{
+			name: "chained symlinks escaping as parent",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarDir("d/"), tarSymlink("d/b", "../../../.."), tarSymlink("a", "d/b"), tarFile("a/evil", "x")}
+			},
+			inside: []string{"evil"},
+		},
+		{
+			name: "symlink replaced by a file",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{tarSymlink("secret", filepath.Join(outside, "secret")), tarFile("secret", "overwritten")}
+			},
+			inside: []string{"secret"},
+		},
+		{
+			name: "symlink replaced by a directory",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{tarSymlink("a", outside), tarDir("a/"), tarFile("a/evil", "x")}
+			},
+			inside: []string{"a/evil"},
+		},
+		{
+			name: "parent directory in the name",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarFile("../outside/evil", "x"), tarFile("ok", "x")}
+			},
+			inside: []string{"ok"},
+		},
+		{
+			name: "hardlink escaping the root",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarHardlink("evil", "../outside/secret"), tarFile("ok", "x")}
+			},
+			inside: []string{"ok"},
+		},
+		{
+			name: "hardlink through an absolute symlink",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{tarSymlink("a", outside), tarHardlink("evil", "a/secret")}
+			},
+			wantErr: os.ErrNotExist,
+		},
+		{
+			name: "symlink loop",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarSymlink("a", "b"), tarSymlink("b", "a"), tarFile("a/evil", "x")}
+			},
+			wantErr: errors.New("too many levels of symbolic links"),
+		},
+		{
+			name: "strict absolute symlink",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{tarSymlink("a", outside)}
+			},
+			strict:  true,
+			wantErr: ErrUnsafePath,
+		},
+		{
+			name: "strict relative symlink escaping",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarDir("a/"), tarSymlink("a/b", "../../outside")}
+			},
+			strict:  true,
+			wantErr: ErrUnsafePath,
+		},
+		{
+			name: "strict parent directory in the name",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarFile("../outside/evil", "x")}
+			},
+			strict:  true,
+			wantErr: ErrUnsafePath,
+		},
+		{
+			name: "strict hardlink escaping the root",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarHardlink("evil", "../outside/secret")}
+			},
+			strict:  true,
+			wantErr: ErrUnsafePath,
+		},
+		{
+			name: "hardlink to the root",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarFile("a", "x"), tarHardlink("l", "/"), tarFile("b", "x")}
+			},
+			inside: []string{"a", "b"},
+		},
+		{
+			name: "strict hardlink to the root",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarFile("a", "x"), tarHardlink("l", "/"), tarFile("b", "x")}
+			},
+			strict:  true,
+			wantErr: ErrUnsafePath,
+		},
+		{
+			name: "strict hardlink to the current directory",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarFile("a", "x"), tarHardlink("l", "."), tarFile("b", "x")}
+			},
+			strict:  true,
+			wantErr: ErrUnsafePath,
+		},
+		{
+			name: "strict absolute hardlink",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarFile("a", "x"), tarHardlink("l", "/a")}
+			},
+			strict:  true,
+			wantErr: ErrUnsafePath,
+		},
+		{
+			name: "strict root entry",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarDir("./"), tarDir("./etc/"), tarFile("./etc/hosts", "x")}
+			},
+			strict: true,
+			inside: []string{"etc/hosts"},
+		},
+		{
+			name: "strict relative symlink inside",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarDir("usr/"), tarDir("usr/lib/"), tarFile("usr/lib/libfoo.so.1", "x"),
+					tarSymlink("usr/lib/libfoo.so", "libfoo.so.1"), tarSymlink("lib", "usr/lib"), tarFile("lib/extra", "x")}
+			},
+			strict: true,
+			inside: []string{"usr/lib/libfoo.so", "usr/lib/extra"},
+		},
+		{
+			name: "with-deps absolute symlink as parent",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{tarSymlink("lib", outside), tarFile("lib/passwd", "x")}
+			},
+			withDeps: true,
+			inside:   []string{"lib", "$OUTSIDE/passwd"},
+		},
+		{
+			name: "with-deps relative symlink escaping as parent",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarSymlink("lib", "../outside"), tarFile("lib/evil", "x")}
+			},
+			withDeps: true,
+			inside:   []string{"outside/evil"},
+		},
+		{
+			name: "with-deps hardlink escaping the root",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarHardlink("evil", "../outside/secret")}
+			},
+			withDeps: true,
+			wantErr:  os.ErrNotExist,
+		},
+		{
+			name: "with-deps strict absolute symlink",
+			entries: func(outside string) []tarEntry {
+				return []tarEntry{tarSymlink("lib", outside), tarFile("lib/passwd", "x")}
+			},
+			withDeps: true,
+			strict:   true,
+			wantErr:  ErrUnsafePath,
+		},
+		{
+			name: "with-deps strict hardlink escaping the root",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarHardlink("evil", "../outside/secret")}
+			},
+			withDeps: true,
+			strict:   true,
+			wantErr:  ErrUnsafePath,
+		},
+		{
+			name: "with-deps strict hardlink to the root",
+			entries: func(string) []tarEntry {
+				return []tarEntry{tarFile("a", "x"), tarHardlink("l", "/")}
+			},
+			withDeps: true,
+			strict:   true,
+			wantErr:  ErrUnsafePath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, outside := setupRoots(t)
+
+			rc := buildTar(t, tt.entries(outside)...)
+
+			var err error
+			if tt.withDeps {
+				err = copyLayout(rc, output, tt.strict)
+			} else {
+				_, err = CopyTar(context.Background(), rc, output, &ExtractOptions{Strict: tt.strict})
+			}
+
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != nil && err == nil:
+				t.Fatalf("expected error '%v', got none", tt.wantErr)
+			case tt.wantErr != nil && !errors.Is(err, tt.wantErr) && !strings.Contains(err.Error(), tt.wantErr.Error()):
+				t.Fatalf("expected error '%v', got '%v'", tt.wantErr, err)
+			}
+
+			// Nothing may change outside of the output directory
+			entries, err := os.ReadDir(outside)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 || entries[0].Name() != "secret" {
+				t.Errorf("files written outside the output directory: %v", entries)
+			}
+
+			secret, err := os.ReadFile(filepath.Join(outside, "secret"))
+			if err != nil || string(secret) != "secret" {
+				t.Errorf("the file outside the output directory is changed: %q, %v", secret, err)
+			}
+
+			if _, err = os.Stat(filepath.Join(filepath.Dir(output), "evil")); err == nil {
+				t.Errorf("file written next to the output directory")
+			}
+
+			for _, p := range tt.inside {
+				p = strings.ReplaceAll(p, "$OUTSIDE", filepath.ToSlash(outside))
+				if _, err = os.Lstat(filepath.Join(output, filepath.FromSlash(p))); err != nil {
+					t.Errorf("expected '%s' inside the output directory: %v", p, err)
+				}
+			}
+		})
+	}
+}
+
+// copyLayout writes every entry at its image path like cp --with-deps
+func copyLayout(rc io.ReadCloser, output string, strict bool) error {
+	return WalkTar(rc, func(r io.Reader, header *tar.Header) error {
+		target, err := LayoutTarget(output, header, strict)
+		if err != nil {
+			return err
+		}
+
+		return CopyLayoutEntry(r, header, output, target)
+	})
+}
+
+func TestSecureJoin(t *testing.T) {
+	output, outside := setupRoots(t)
+
+	links := map[string]string{
+		"abs":      outside,
+		"escape":   "../../..",
+		"relative": "sub",
+		"chain":    "escape/relative",
+	}
+	if err := os.Mkdir(filepath.Join(output, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(output, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "file", want: "file"},
+		{name: "/abs/file", want: filepath.Join(outside, "file")},
+		{name: "escape/file", want: "file"},
+		{name: "relative/file", want: "sub/file"},
+		{name: "chain/file", want: "sub/file"},
+		{name: "../../file", want: "file"},
+		{name: "missing/../relative/file", want: "sub/file"},
+		// The last component is the entry itself and is not followed
+		{name: "abs", want: "abs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SecureJoin(output, tt.name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if want := filepath.Join(output, filepath.FromSlash(tt.want)); got != want {
+				t.Errorf("SecureJoin(%q) = %q, want %q", tt.name, got, want)
+			}
+		})
+	}
+}
+
+func TestCheckLink(t *testing.T) {
+	tests := []struct {
+		header *tar.Header
+		unsafe bool
+	}{
+		{header: &tar.Header{Name: "usr/lib/libfoo.so", Typeflag: tar.TypeSymlink, Linkname: "libfoo.so.1"}},
+		{header: &tar.Header{Name: "etc/localtime", Typeflag: tar.TypeSymlink, Linkname: "/usr/share/zoneinfo/UTC"}, unsafe: true},
+		{header: &tar.Header{Name: "usr/bin/python", Typeflag: tar.TypeLink, Linkname: "usr/bin/python3"}},
+		{header: &tar.Header{Name: "usr/bin/python", Typeflag: tar.TypeLink, Linkname: "./usr/bin/python3"}},
+		{header: &tar.Header{Name: "evil", Typeflag: tar.TypeLink, Linkname: "../outside/secret"}, unsafe: true},
+		{header: &tar.Header{Name: "evil", Typeflag: tar.TypeLink, Linkname: "/etc/passwd"}, unsafe: true},
+		{header: &tar.Header{Name: "evil", Typeflag: tar.TypeLink, Linkname: "/"}, unsafe: true},
+		{header: &tar.Header{Name: "evil", Typeflag: tar.TypeLink, Linkname: "."}, unsafe: true},
+		{header: &tar.Header{Name: "evil", Typeflag: tar.TypeLink, Linkname: ""}, unsafe: true},
+		{header: &tar.Header{Name: "file", Typeflag: tar.TypeReg}},
+	}
+
+	for _, tt := range tests {
+		err := CheckLink(tt.header)
+		if unsafe := errors.Is(err, ErrUnsafePath); unsafe != tt.unsafe {
+			t.Errorf("CheckLink('%s' -> '%s') = %v, want unsafe %v", tt.header.Name, tt.header.Linkname, err, tt.unsafe)
+		}
+	}
+}
+
+func TestIsUnsafeLink(t *testing.T) {
+	tests := []struct {
+		name     string
+		linkname string
+		want     bool
+	}{
+		{name: "usr/lib/libfoo.so", linkname: "libfoo.so.1", want: false},
+		{name: "usr/lib/libfoo.so", linkname: "../../lib/libfoo.so.1", want: false},
+		{name: "usr/lib/libfoo.so", linkname: "../../../lib/libfoo.so.1", want: true},
+		{name: "etc/localtime", linkname: "/usr/share/zoneinfo/UTC", want: true},
+		{name: "a", linkname: "..", want: true},
+		{name: "a", linkname: ".", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsUnsafeLink(tt.name, tt.linkname); got != tt.want {
+			t.Errorf("IsUnsafeLink(%q, %q) = %v, want %v", tt.name, tt.linkname, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxSymlinks is the maximum number of symlinks followed while resolving a path (same as Linux)
+const maxSymlinks = 40
+
+var ErrNotExist = errors.New("path does not exist")
+
+// FileTree is an in-memory index of the image filesystem built from tar headers
+type FileTree struct {
+	entries map[string]*tar.Header
+}
+
+// NewFileTree creates an empty file tree
+func NewFileTree() *FileTree {
+	return &FileTree{entries: make(map[string]*tar.Header)}
+}
+
+// CleanPath normalizes a tar entry name to a slash separated path relative to the image root
+func CleanPath(name string) string {
+	cleaned := path.Clean("/" + name)
+	if cleaned == "/" {
+		return ""
+	}
+
+	return cleaned[1:]
+}
+
+// Add adds the tar entry to the tree, replacing an existing entry with the same path
+func (t *FileTree) Add(header *tar.Header) {
+	t.entries[CleanPath(header.Name)] = header
+}
+
+// Get returns the tar header of the path
+func (t *FileTree) Get(p string) (*tar.Header, bool) {
+	header, ok := t.entries[CleanPath(p)]
+	return header, ok
+}
+
+// Glob returns sorted paths matching the pattern (see path.Match)
+func (t *FileTree) Glob(pattern string) []string {
+	pattern = CleanPath(pattern)
+
+	var matches []string
+	for p := range t.entries {
+		if ok, _ := path.Match(pattern, p); ok {
+			matches = append(matches, p)
+		}
+	}
+
+	sort.Strings(matches)
+
+	return matches
+}
+
+// Resolve resolves all symlinks in the path within the image root.
+// It returns the resolved path and the symlinks traversed during resolution
+func (t *FileTree) Resolve(p string) (string, []string, error) {
+	var links []string
+
+	resolved := ""
+	remaining := strings.Split(CleanPath(p), "/")
+	followed := 0
+
+	for len(remaining) > 0 {
+		component := remaining[0]
+		remaining = remaining[1:]
+
+		switch component {
+		case "", ".":
+			continue
+		case "..":
+			resolved = CleanPath(path.Dir("/" + resolved))
+			continue
+		}
+
+		current := CleanPath(resolved + "/" + component)
+		header, ok := t.entries[current]
+		if !ok {
+			return "", nil, fmt.Errorf("resolve '%s': %w", p, ErrNotExist)
+		}
+
+		if header.Typeflag != tar.TypeSymlink {
+			resolved = current
+			continue
+		}
+
+		followed++
+		if followed > maxSymlinks {
+			return "", nil, fmt.Errorf("resolve '%s': too many levels of symbolic links", p)
+		}
+
+		links = append(links, current)
+
+		// Absolute targets restart from the image root, relative ones from the link directory
+		if strings.HasPrefix(header.Linkname, "/") {
+			resolved = ""
+		}
+
+		remaining = append(strings.Split(header.Linkname, "/"), remaining...)
+	}
+
+	return resolved, links, nil
+}
@@ -0,0 +1,187 @@
+package tools
+
+import (
+	"fmt"
+	"strings"
+)
+
+// lineOp is a single edit of a line diff.
+// Positions are the line indexes in the old and new texts before the edit applies
+type lineOp struct {
+	kind byte // ' ' (equal), '-' (delete) or '+' (insert)
+	a, b int
+}
+
+// UnifiedDiff returns unified diff hunks ('@@ ... @@' blocks) between two texts
+// with the given number of context lines, empty if the texts are equal
+func UnifiedDiff(oldText, newText string, context int) string {
+	a, b := splitLines(oldText), splitLines(newText)
+	ops := diffLines(a, b)
+
+	var sb strings.Builder
+	for i := 0; i < len(ops); {
+		// Find the next change
+		for i < len(ops) && ops[i].kind == ' ' {
+			i++
+		}
+		if i == len(ops) {
+			break
+		}
+
+		start := max(i-context, 0)
+
+		// Extend the hunk while changes are close enough to share context
+		last := i
+		for j := i; j < len(ops) && j-last <= 2*context; j++ {
+			if ops[j].kind != ' ' {
+				last = j
+			}
+		}
+		end := min(last+context+1, len(ops))
+
+		writeHunk(&sb, a, b, ops[start:end])
+		i = end
+	}
+
+	return sb.String()
+}
+
+// writeHunk writes a single hunk with its header
+func writeHunk(sb *strings.Builder, a, b []string, ops []lineOp) {
+	var oldCount, newCount int
+	for _, op := range ops {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+
+	oldStart, newStart := ops[0].a+1, ops[0].b+1
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+
+	sb.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount)))
+
+	for _, op := range ops {
+		var line string
+		if op.kind == '+' {
+			line = b[op.b]
+		} else {
+			line = a[op.a]
+		}
+
+		sb.WriteByte(op.kind)
+		sb.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			sb.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// hunkRange formats a hunk range, the count is omitted when it is one
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits the text into lines keeping the line endings
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
+
+// diffLines computes the shortest edit script between two line lists using the Myers algorithm
+func diffLines(a, b []string) []lineOp {
+	n, m := len(a), len(b)
+	limit := n + m
+	offset := limit + 1
+
+	v := make([]int, 2*limit+2)
+	var trace [][]int
+
+search:
+	for d := 0; d <= limit; d++ {
+		// Only diagonals reachable in d steps are needed to walk back
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace back from the end to recover the edits
+	var ops []lineOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		var prevX, prevY int
+		if d > 0 {
+			v := trace[d]
+			k := x - y
+
+			prevK := k - 1
+			if k == -d || (k != d && v[k-1+d] < v[k+1+d]) {
+				prevK = k + 1
+			}
+
+			prevX = v[prevK+d]
+			prevY = prevX - prevK
+		}
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, lineOp{kind: ' ', a: x, b: y})
+		}
+
+		if d == 0 {
+			break
+		}
+
+		if x == prevX {
+			y--
+			ops = append(ops, lineOp{kind: '+', a: x, b: y})
+		} else {
+			x--
+			ops = append(ops, lineOp{kind: '-', a: x, b: y})
+		}
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+
+	return ops
+}
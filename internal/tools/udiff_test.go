@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	lines := func(from, to int, replace map[int]string) string {
+		var b strings.Builder
+		for i := from; i <= to; i++ {
+			if s, ok := replace[i]; ok {
+				b.WriteString(s + "\n")
+				continue
+			}
+			b.WriteString(strconv.Itoa(i) + "\n")
+		}
+		return b.String()
+	}
+
+	// Expected output matches `git diff` for the same inputs
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "added file",
+			old:  "",
+			new:  "a\nb\n",
+			want: "@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "deleted file",
+			old:  "a\nb\n",
+			new:  "",
+			want: "@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name: "added line",
+			old:  "a\nb\n",
+			new:  "a\nb\nc\n",
+			want: "@@ -1,2 +1,3 @@\n a\n b\n+c\n",
+		},
+		{
+			name: "deleted line",
+			old:  "a\nb\nc\n",
+			new:  "a\nc\n",
+			want: "@@ -1,3 +1,2 @@\n a\n-b\n c\n",
+		},
+		{
+			name: "no trailing newline",
+			old:  "a\nb\nc",
+			new:  "a\nb\nd",
+			want: "@@ -1,3 +1,3 @@\n a\n b\n-c\n\\ No newline at end of file\n+d\n\\ No newline at end of file\n",
+		},
+		{
+			name: "trailing newline added",
+			old:  "a\nb",
+			new:  "a\nb\n",
+			want: "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name: "hunk at end of file",
+			old:  lines(1, 10, nil),
+			new:  lines(1, 10, map[int]string{10: "ten"}),
+			want: "@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+		{
+			name: "separate hunks",
+			old:  lines(1, 20, nil),
+			new:  lines(1, 20, map[int]string{2: "two", 18: "eighteen"}),
+			want: "@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+				"@@ -15,6 +15,6 @@\n 15\n 16\n 17\n-18\n+eighteen\n 19\n 20\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff(tt.old, tt.new, 3); got != tt.want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		content []byte
+		want    bool
+	}{
+		{name: "empty", content: nil, want: false},
+		{name: "text", content: []byte("hello\nworld\n"), want: false},
+		{name: "utf-8", content: []byte("héllo ✓\n"), want: false},
+		{name: "nul byte", content: []byte("ELF\x00\x01"), want: true},
+		{name: "nul byte past the first 8000 bytes", content: []byte(strings.Repeat("a", 8000) + "\x00"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBinary(tt.content); got != tt.want {
+				t.Errorf("IsBinary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
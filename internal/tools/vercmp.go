@@ -0,0 +1,329 @@
+package tools
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CompareVersions compares two package versions using the rules of the ecosystem.
+// It returns -1 if a < b, 0 if a == b and 1 if a > b
+func CompareVersions(ecosystem, a, b string) int {
+	switch EcosystemBase(ecosystem) {
+	case "Debian", "Ubuntu":
+		return CompareDebianVersions(a, b)
+	case "Alpine":
+		return CompareAPKVersions(a, b)
+	default:
+		return CompareSemver(a, b)
+	}
+}
+
+// EcosystemBase returns the ecosystem without the release suffix (e.g. 'Debian:12' -> 'Debian')
+func EcosystemBase(ecosystem string) string {
+	base, _, _ := strings.Cut(ecosystem, ":")
+	return base
+}
+
+// CompareSemver compares semantic versions, the leading 'v' is optional
+func CompareSemver(a, b string) int {
+	a, _, _ = strings.Cut(strings.TrimPrefix(a, "v"), "+")
+	b, _, _ = strings.Cut(strings.TrimPrefix(b, "v"), "+")
+
+	aCore, aPre, aHasPre := strings.Cut(a, "-")
+	bCore, bPre, bHasPre := strings.Cut(b, "-")
+
+	if res := compareCore(aCore, bCore); res != 0 {
+		return res
+	}
+
+	// A version without pre-release has higher precedence
+	switch {
+	case !aHasPre && !bHasPre:
+		return 0
+	case !aHasPre:
+		return 1
+	case !bHasPre:
+		return -1
+	}
+
+	return compareDotted(aPre, bPre, comparePreRelease)
+}
+
+// compareDotted compares dot separated identifiers one by one, a shorter list has lower precedence
+func compareDotted(a, b string, cmp func(a, b string) int) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+
+	for i := 0; i < len(aParts) && i < len(bParts); i++ {
+		if res := cmp(aParts[i], bParts[i]); res != 0 {
+			return res
+		}
+	}
+
+	return compareInt(len(aParts), len(bParts))
+}
+
+// compareCore compares dot separated numbers, missing components are treated as zero
+func compareCore(a, b string) int {
+	aParts := strings.Split(a, ".")
+	bParts := strings.Split(b, ".")
+
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var aPart, bPart string
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+
+		if res := compareNumberStrings(aPart, bPart); res != 0 {
+			return res
+		}
+	}
+
+	return 0
+}
+
+// comparePreRelease compares pre-release identifiers, numeric ones have lower precedence
+func comparePreRelease(a, b string) int {
+	aNum, aErr := strconv.Atoi(a)
+	bNum, bErr := strconv.Atoi(b)
+
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInt(aNum, bNum)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+// CompareDebianVersions compares versions the same way as dpkg ([epoch:]upstream[-revision])
+func CompareDebianVersions(a, b string) int {
+	aEpoch, aUpstream, aRevision := splitDebianVersion(a)
+	bEpoch, bUpstream, bRevision := splitDebianVersion(b)
+
+	if res := compareInt(aEpoch, bEpoch); res != 0 {
+		return res
+	}
+
+	if res := compareDebianPart(aUpstream, bUpstream); res != 0 {
+		return res
+	}
+
+	return compareDebianPart(aRevision, bRevision)
+}
+
+// splitDebianVersion splits the version into epoch, upstream version and revision
+func splitDebianVersion(version string) (int, string, string) {
+	var epoch int
+	if before, after, ok := strings.Cut(version, ":"); ok {
+		epoch, _ = strconv.Atoi(before)
+		version = after
+	}
+
+	if i := strings.LastIndexByte(version, '-'); i >= 0 {
+		return epoch, version[:i], version[i+1:]
+	}
+
+	return epoch, version, ""
+}
+
+// compareDebianPart compares alternating non-digit and digit parts, see dpkg verrevcmp
+func compareDebianPart(a, b string) int {
+	for a != "" || b != "" {
+		var aText, bText string
+		aText, a = splitPrefix(a, false)
+		bText, b = splitPrefix(b, false)
+
+		if res := compareDebianText(aText, bText); res != 0 {
+			return res
+		}
+
+		var aNum, bNum string
+		aNum, a = splitPrefix(a, true)
+		bNum, b = splitPrefix(b, true)
+
+		if res := compareNumberStrings(aNum, bNum); res != 0 {
+			return res
+		}
+	}
+
+	return 0
+}
+
+// compareDebianText compares non-digit parts: '~' sorts before anything, letters before other symbols
+func compareDebianText(a, b string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var aOrder, bOrder int
+		if i < len(a) {
+			aOrder = debianOrder(a[i])
+		}
+		if i < len(b) {
+			bOrder = debianOrder(b[i])
+		}
+
+		if res := compareInt(aOrder, bOrder); res != 0 {
+			return res
+		}
+	}
+
+	return 0
+}
+
+// debianOrder returns the sort weight of a character in a Debian version
+func debianOrder(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case isDigit(c):
+		return 0
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	default:
+		return int(c) + 256
+	}
+}
+
+// CompareAPKVersions compares Alpine package versions (digits, letter, suffixes and -r revision)
+func CompareAPKVersions(a, b string) int {
+	aVersion := parseAPKVersion(a)
+	bVersion := parseAPKVersion(b)
+
+	for i := 0; i < len(aVersion.numbers) && i < len(bVersion.numbers); i++ {
+		if res := compareInt(aVersion.numbers[i], bVersion.numbers[i]); res != 0 {
+			return res
+		}
+	}
+
+	if res := compareInt(len(aVersion.numbers), len(bVersion.numbers)); res != 0 {
+		return res
+	}
+
+	if res := compareInt(int(aVersion.letter), int(bVersion.letter)); res != 0 {
+		return res
+	}
+
+	for i := 0; i < len(aVersion.suffixes) || i < len(bVersion.suffixes); i++ {
+		aSuffix, bSuffix := apkSuffix{rank: apkNoSuffix}, apkSuffix{rank: apkNoSuffix}
+		if i < len(aVersion.suffixes) {
+			aSuffix = aVersion.suffixes[i]
+		}
+		if i < len(bVersion.suffixes) {
+			bSuffix = bVersion.suffixes[i]
+		}
+
+		if res := compareInt(aSuffix.rank, bSuffix.rank); res != 0 {
+			return res
+		}
+
+		if res := compareInt(aSuffix.number, bSuffix.number); res != 0 {
+			return res
+		}
+	}
+
+	return compareInt(aVersion.revision, bVersion.revision)
+}
+
+// apkSuffixes defines the order of version suffixes, a version without suffix sorts in the middle
+var apkSuffixes = map[string]int{
+	"alpha": 0,
+	"beta":  1,
+	"pre":   2,
+	"rc":    3,
+	"cvs":   5,
+	"svn":   6,
+	"git":   7,
+	"hg":    8,
+	"p":     9,
+}
+
+const apkNoSuffix = 4
+
+type apkSuffix struct {
+	rank   int
+	number int
+}
+
+type apkVersion struct {
+	numbers  []int
+	letter   byte
+	suffixes []apkSuffix
+	revision int
+}
+
+// parseAPKVersion parses versions like '1.2.3b_rc1_p2-r4'
+func parseAPKVersion(version string) apkVersion {
+	var res apkVersion
+
+	if i := strings.LastIndex(version, "-r"); i >= 0 {
+		res.revision, _ = strconv.Atoi(version[i+2:])
+		version = version[:i]
+	}
+
+	parts := strings.Split(version, "_")
+	for _, number := range strings.Split(parts[0], ".") {
+		digits, rest := splitPrefix(number, true)
+		n, _ := strconv.Atoi(digits)
+		res.numbers = append(res.numbers, n)
+
+		if rest != "" {
+			res.letter = rest[0]
+		}
+	}
+
+	for _, suffix := range parts[1:] {
+		name := strings.TrimRightFunc(suffix, func(r rune) bool { return r >= '0' && r <= '9' })
+		n, _ := strconv.Atoi(suffix[len(name):])
+
+		rank, ok := apkSuffixes[name]
+		if !ok {
+			rank = apkNoSuffix
+		}
+
+		res.suffixes = append(res.suffixes, apkSuffix{rank: rank, number: n})
+	}
+
+	return res
+}
+
+// splitPrefix splits the leading digits (or non-digits) from the rest of the string
+func splitPrefix(s string, digits bool) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) == digits {
+		i++
+	}
+
+	return s[:i], s[i:]
+}
+
+// compareNumberStrings compares arbitrary long decimal numbers, empty string is zero
+func compareNumberStrings(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+
+	if res := compareInt(len(a), len(b)); res != 0 {
+		return res
+	}
+
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
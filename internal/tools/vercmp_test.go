@@ -0,0 +1,64 @@
+package tools
+
+import "testing"
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		ecosystem string
+		a, b      string
+		want      int
+	}{
+		// Debian: epochs, '~', revisions and the dpkg ordering of letters and symbols
+		{ecosystem: "Debian:12", a: "1.0", b: "1.0", want: 0},
+		{ecosystem: "Debian:12", a: "0:1.0-1", b: "1.0-1", want: 0},
+		{ecosystem: "Debian:12", a: "1:1.0", b: "2.0", want: 1},
+		{ecosystem: "Debian:12", a: "1:1.0-1", b: "2:0.1-1", want: -1},
+		{ecosystem: "Debian:12", a: "1.0~rc1", b: "1.0", want: -1},
+		{ecosystem: "Debian:12", a: "1.0~rc1", b: "1.0~rc2", want: -1},
+		{ecosystem: "Debian:12", a: "1.0~~", b: "1.0~", want: -1},
+		{ecosystem: "Debian:12", a: "1.0~", b: "1.0", want: -1},
+		{ecosystem: "Debian:12", a: "1.0", b: "1.0-1", want: -1},
+		{ecosystem: "Debian:12", a: "1.0a", b: "1.0", want: 1},
+		{ecosystem: "Debian:12", a: "1.0+b1", b: "1.0", want: 1},
+		{ecosystem: "Debian:12", a: "1.0.1", b: "1.0+b1", want: 1},
+		{ecosystem: "Debian:12", a: "2.36-9+deb12u10", b: "2.36-9+deb12u9", want: 1},
+		{ecosystem: "Debian:12", a: "1.0-1ubuntu1", b: "1.0-1", want: 1},
+		{ecosystem: "Debian:12", a: "1.2-3-4", b: "1.2-3-10", want: -1},
+		{ecosystem: "Ubuntu:22.04", a: "1.1.1f-1ubuntu2.20", b: "1.1.1f-1ubuntu2.3", want: 1},
+
+		// Alpine: letters, '_' suffixes and '-r' revisions
+		{ecosystem: "Alpine:v3.19", a: "1.2.3-r0", b: "1.2.3-r0", want: 0},
+		{ecosystem: "Alpine:v3.19", a: "1.2.3-r0", b: "1.2.3-r1", want: -1},
+		{ecosystem: "Alpine:v3.19", a: "3.0.8-r0", b: "3.0.10-r0", want: -1},
+		{ecosystem: "Alpine:v3.19", a: "1.2", b: "1.2.1", want: -1},
+		{ecosystem: "Alpine:v3.19", a: "1.2.3_rc1", b: "1.2.3", want: -1},
+		{ecosystem: "Alpine:v3.19", a: "1.2.3_rc1", b: "1.2.3_beta2", want: 1},
+		{ecosystem: "Alpine:v3.19", a: "1.2.3_rc10", b: "1.2.3_rc2", want: 1},
+		{ecosystem: "Alpine:v3.19", a: "1.2.3_rc1-r2", b: "1.2.3_rc1-r1", want: 1},
+		{ecosystem: "Alpine:v3.19", a: "1.2.3_p1", b: "1.2.3", want: 1},
+		{ecosystem: "Alpine:v3.19", a: "1.2.3a", b: "1.2.3", want: 1},
+		{ecosystem: "Alpine:v3.19", a: "1.2.3b", b: "1.2.3a", want: 1},
+
+		// Semantic versions for everything else
+		{ecosystem: "Go", a: "v1.2.3", b: "1.2.3", want: 0},
+		{ecosystem: "Go", a: "1.2", b: "1.2.0", want: 0},
+		{ecosystem: "Go", a: "1.2.3", b: "1.10.0", want: -1},
+		{ecosystem: "Go", a: "1.0.0+build.5", b: "1.0.0", want: 0},
+		{ecosystem: "Go", a: "1.0.0-alpha", b: "1.0.0", want: -1},
+		{ecosystem: "Go", a: "1.0.0-alpha", b: "1.0.0-alpha.1", want: -1},
+		{ecosystem: "Go", a: "1.0.0-alpha.1", b: "1.0.0-alpha.beta", want: -1},
+		{ecosystem: "Go", a: "1.0.0-beta.2", b: "1.0.0-beta.11", want: -1},
+		{ecosystem: "npm", a: "10.0.0", b: "9.99.99", want: 1},
+	}
+
+	for _, tt := range tests {
+		if got := CompareVersions(tt.ecosystem, tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q, %q) = %d, want %d", tt.ecosystem, tt.a, tt.b, got, tt.want)
+		}
+
+		// The order must not depend on the side a version is passed on
+		if got := CompareVersions(tt.ecosystem, tt.b, tt.a); got != -tt.want {
+			t.Errorf("CompareVersions(%q, %q, %q) = %d, want %d", tt.ecosystem, tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
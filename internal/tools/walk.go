@@ -5,12 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"strings"
 )
 
 var ErrStopWalk = errors.New("stop walk")
 
-// WalkTar walks over the tar archive
+// WalkTar walks over the tar archive.
+// Whiteout markers are skipped, use ReadLayer to interpret them
 func WalkTar(rc io.ReadCloser, f func(r io.Reader, header *tar.Header) error) error {
 	reader := tar.NewReader(rc)
 	for {
@@ -22,8 +22,8 @@ func WalkTar(rc io.ReadCloser, f func(r io.Reader, header *tar.Header) error) er
 			return fmt.Errorf("read tar header: %w", err)
 		}
 
-		// Skip whiteout files (Docker layer deletion markers)
-		if strings.Contains(header.Name, ".wh.") {
+		// Skip whiteout and opaque directory markers of a raw layer
+		if IsWhiteout(header.Name) {
 			continue
 		}
 
@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"path"
+	"strings"
+)
+
+const (
+	// WhiteoutPrefix marks a file deleted in the layer ('.wh.<name>')
+	WhiteoutPrefix = ".wh."
+	// WhiteoutOpaque marks a directory whose lower layer contents are hidden
+	WhiteoutOpaque = WhiteoutPrefix + WhiteoutPrefix + ".opq"
+)
+
+// IsWhiteout checks if the tar entry is a whiteout or an opaque directory marker.
+// Only the base name is checked, so files like 'config.wh.json' are regular files
+func IsWhiteout(name string) bool {
+	return strings.HasPrefix(path.Base(CleanPath(name)), WhiteoutPrefix)
+}
+
+// WhiteoutTarget returns the path deleted by the whiteout entry
+func WhiteoutTarget(name string) (string, bool) {
+	cleaned := CleanPath(name)
+	dir, base := path.Split(cleaned)
+	if !strings.HasPrefix(base, WhiteoutPrefix) || base == WhiteoutOpaque {
+		return "", false
+	}
+
+	return dir + strings.TrimPrefix(base, WhiteoutPrefix), true
+}
+
+// OpaqueDir returns the directory marked as opaque by the entry
+func OpaqueDir(name string) (string, bool) {
+	cleaned := CleanPath(name)
+	if path.Base(cleaned) != WhiteoutOpaque {
+		return "", false
+	}
+
+	return CleanPath(path.Dir(cleaned)), true
+}